@@ -0,0 +1,101 @@
+package gree
+
+import (
+	"github.com/fatih/color"
+)
+
+// Cell is a single column of a drawing along with the color
+// attributes it is drawn with
+type Cell struct {
+	// Rune is 0 in the second column of a wide rune
+	Rune rune
+	// Fg and Bg are the foreground and background colors,
+	// zero when the cell uses the terminal default
+	Fg color.Attribute
+	Bg color.Attribute
+	// FgExt and BgExt hold the rest of a 256 color (5, n) or
+	// 24 bit color (2, r, g, b) sequence, in which case Fg is
+	// 38 or Bg is 48
+	FgExt []color.Attribute
+	BgExt []color.Attribute
+	// Attrs holds the remaining attributes like color.Bold
+	Attrs []color.Attribute
+}
+
+// RenderCells returns the lines of DrawOptions(di) as rows of
+// cells. Colors are decoded whether or not color output is
+// enabled. The legend and ruler are not included.
+func (n *Node) RenderCells(di *DrawInput) [][]Cell {
+	lines, _ := n.drawLines(di)
+	cells := make([][]Cell, len(lines))
+	for i, line := range lines {
+		if line.row == nil {
+			for _, r := range line.text {
+				cells[i] = append(cells[i], Cell{Rune: r})
+			}
+			continue
+		}
+		for x := 0; x <= line.row.width; x++ {
+			cells[i] = append(cells[i], newCell(line.row.contents[x], line.row.colors[x]))
+		}
+	}
+	return cells
+}
+
+// newCell sorts attrs into the colors and other attributes
+// of a cell. Later colors win like they do on a terminal.
+func newCell(r rune, attrs []color.Attribute) Cell {
+	if r == wideTail {
+		r = 0
+	}
+	cell := Cell{Rune: r}
+	for i := 0; i < len(attrs); i++ {
+		attr := attrs[i]
+		switch {
+		case isFgColor(attr):
+			cell.Fg, cell.FgExt = attr, nil
+		case isBgColor(attr):
+			cell.Bg, cell.BgExt = attr, nil
+		case attr == 38 || attr == 48:
+			ext := extColorLen(attrs[i+1:])
+			if ext == 0 {
+				// malformed, keep it as is
+				cell.Attrs = append(cell.Attrs, attr)
+				continue
+			}
+			params := append([]color.Attribute(nil), attrs[i+1:i+1+ext]...)
+			if attr == 38 {
+				cell.Fg, cell.FgExt = attr, params
+			} else {
+				cell.Bg, cell.BgExt = attr, params
+			}
+			i += ext
+		default:
+			cell.Attrs = append(cell.Attrs, attr)
+		}
+	}
+	return cell
+}
+
+// extColorLen returns how many of params belong to the 256
+// color or 24 bit color that a 38 or 48 before them starts,
+// or 0 when they don't make a complete color
+func extColorLen(params []color.Attribute) int {
+	switch {
+	case len(params) >= 2 && params[0] == 5:
+		return 2
+	case len(params) >= 4 && params[0] == 2:
+		return 4
+	}
+	return 0
+}
+
+func isFgColor(attr color.Attribute) bool {
+	return (attr >= color.FgBlack && attr <= color.FgWhite) ||
+		(attr >= color.FgHiBlack && attr <= color.FgHiWhite)
+}
+
+func isBgColor(attr color.Attribute) bool {
+	return (attr >= color.BgBlack && attr <= color.BgWhite) ||
+		(attr >= color.BgHiBlack && attr <= color.BgHiWhite)
+}
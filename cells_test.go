@@ -0,0 +1,58 @@
+package gree
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestRenderCells(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("ab").SetColors(color.FgRed, color.Bold).SetColors(color.BgBlue)
+	cells := a.RenderCells(&DrawInput{Border: true})
+	if len(cells) != 4 {
+		t.Fatalf("expected 4 rows, got %d", len(cells))
+	}
+	for i, row := range cells {
+		if len(row) != len(cells[0]) {
+			t.Errorf("row %d has %d cells, expected %d", i, len(row), len(cells[0]))
+		}
+	}
+	if cells[0][0].Rune != '┌' || cells[1][2].Rune != 'r' {
+		t.Errorf("unexpected runes %q %q", cells[0][0].Rune, cells[1][2].Rune)
+	}
+	label := cells[2][6]
+	if label.Rune != 'a' || label.Fg != color.FgRed || label.Bg != color.BgBlue {
+		t.Errorf("expected red on blue 'a', got %+v", label)
+	}
+	if len(label.Attrs) != 1 || label.Attrs[0] != color.Bold {
+		t.Errorf("expected bold, got %v", label.Attrs)
+	}
+	if branch := cells[2][2]; branch.Rune != '└' || branch.Fg != 0 || len(branch.Attrs) != 0 {
+		t.Errorf("expected uncolored branch, got %+v", branch)
+	}
+}
+
+func TestRenderCellsExtendedColors(t *testing.T) {
+	a := NewNode("root").SetColors(color.Bold, 48, 5, 208)
+	a.NewChild("hot").Heatmap(func(*Node) float64 { return 1 }, [3]uint8{0, 0, 0}, [3]uint8{200, 100, 50})
+	cells := a.RenderCells(&DrawInput{})
+	root := cells[0][0]
+	if root.Bg != 48 || len(root.BgExt) != 2 || root.BgExt[1] != 208 || root.Fg != 0 {
+		t.Errorf("expected a 256 color background, got %+v", root)
+	}
+	if len(root.Attrs) != 1 || root.Attrs[0] != color.Bold {
+		t.Errorf("expected bold, got %v", root.Attrs)
+	}
+	hot := cells[1][4]
+	expected := []color.Attribute{2, 100, 50, 25}
+	if hot.Rune != 'h' || hot.Fg != 38 || hot.Bg != 0 || len(hot.Attrs) != 0 {
+		t.Fatalf("expected a 24 bit foreground, got %+v", hot)
+	}
+	for i, e := range expected {
+		if hot.FgExt[i] != e {
+			t.Errorf("expected %v, got %v", expected, hot.FgExt)
+			break
+		}
+	}
+}
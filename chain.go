@@ -0,0 +1,47 @@
+package gree
+
+// FlattenChain returns a copy of this tree where each run of
+// nodes that have a single child is collapsed into one node
+// whose contents are joined with sep, e.g. "one/two/three".
+// The collapsed node keeps the colors, meta and status of the
+// first node of the run and the children of the last, the
+// rest of the run's properties are lost. In a tree returned
+// by Diff a run ends where the diff kind changes, so added
+// and removed nodes keep rows of their own.
+func (n *Node) FlattenChain(sep string) *Node {
+	return n.flattenChain(sep, false)
+}
+
+// flattenChain is FlattenChain, with linked the nodes are
+// copied with linkedCopy
+func (n *Node) flattenChain(sep string, linked bool) *Node {
+	var nn *Node
+	if linked {
+		nn = n.linkedCopy()
+	} else {
+		nn = n.copyNode(false)
+	}
+	last := n
+	for len(last.children) == 1 && last.children[0].diffKind == n.diffKind {
+		last = last.children[0]
+		nn.contents += sep + last.contents
+	}
+	for _, child := range last.children {
+		nn.insertChild(len(nn.children), child.flattenChain(sep, linked))
+	}
+	nn.drawOrder = append([]int(nil), last.drawOrder...)
+	return nn
+}
+
+// drawChained draws this tree with single child chains
+// collapsed, mapping each collapsed line back to the first
+// node of its chain
+func (n *Node) drawChained(di *DrawInput) (lines []drawnLine, trailer string) {
+	sep := di.ChainSeparator
+	if sep == "" {
+		sep = "/"
+	}
+	sub := *di
+	sub.ChainInline = false
+	return n.drawCopy(n.flattenChain(sep, true), &sub)
+}
@@ -0,0 +1,51 @@
+package gree
+
+import (
+	"strings"
+	"testing"
+)
+
+func chainTree() *Node {
+	a := NewNode("root")
+	a.NewChild("one").NewChild("two").NewChild("three").NewChild("four")
+	b := a.NewChild("src")
+	b.NewChild("main.go")
+	b.NewChild("util").NewChild("strings.go")
+	return a
+}
+
+func TestFlattenChain(t *testing.T) {
+	a := chainTree()
+	expected := "root(one.two.three.four, src(main.go, util.strings.go))"
+	if got := a.FlattenChain(".").Summary(); got != expected {
+		t.Errorf("expected '%s', got '%s'", expected, got)
+	}
+	if a.Height() != 4 {
+		t.Errorf("expected original tree to be untouched")
+	}
+}
+
+func TestChainInline(t *testing.T) {
+	a := chainTree()
+	di := &DrawInput{ChainInline: true}
+	got := strings.Split(a.DrawOptions(di), "\n")
+	expected := []string{
+		"root",
+		"├── one/two/three/four",
+		"└── src",
+		"    ├── main.go",
+		"    └── util/strings.go",
+	}
+	for i, e := range expected {
+		if strings.TrimRight(got[i], " ") != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+		}
+	}
+	if row := a.RenderRowIndex(di)[1]; row != a.GetChild(0) {
+		t.Errorf("expected collapsed row to map to the first node of the chain, got %v", row)
+	}
+	di.ShowIndex = true
+	if got := strings.TrimRight(a.GetChild(1).DrawLines(di)[0], " "); got != "[1] src" {
+		t.Errorf("expected the root to keep its index, got '%s'", got)
+	}
+}
@@ -0,0 +1,138 @@
+package gree
+
+import (
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// DiffKind tells how a node of a Diff tree changed
+type DiffKind int
+
+const (
+	// Unchanged nodes are in both trees
+	Unchanged DiffKind = iota
+	// Added nodes are only in the second tree
+	Added
+	// Removed nodes are only in the first tree
+	Removed
+)
+
+// Diff merges copies of a and b into a single tree where
+// every node is marked with how it changed from a to b, see
+// GetDiffKind. Children are matched by contents in sibling
+// order starting from the roots, which always match. Removed
+// children are placed after the sibling that preceded them
+// in a. A node that moved to another parent shows up as
+// removed in its old place and added in its new one.
+// Unchanged and added nodes keep the ids of b while removed
+// nodes get new ids, so ids stay unique when a and b share
+// them, e.g. when b is a Snapshot of a.
+func Diff(a, b *Node) *Node {
+	merged := b.copyNode(true)
+	merged.diffKind = Unchanged
+	used := make([]bool, len(a.children))
+	next := 0
+	removed := func(upTo int) {
+		for ; next < upTo; next++ {
+			if !used[next] {
+				merged.insertChild(len(merged.children), a.children[next].markedCopy(Removed))
+			}
+		}
+	}
+	for _, bc := range b.children {
+		match := -1
+		for i, ac := range a.children {
+			if !used[i] && ac.contents == bc.contents {
+				match = i
+				break
+			}
+		}
+		if match == -1 {
+			merged.insertChild(len(merged.children), bc.markedCopy(Added))
+			continue
+		}
+		used[match] = true
+		removed(match)
+		merged.insertChild(len(merged.children), Diff(a.children[match], bc))
+	}
+	removed(len(a.children))
+	return merged
+}
+
+// markedCopy copies this node and its descendents, marking
+// each copy with kind. Removed copies come from a, whose ids
+// may be in b as well, so they get new ids.
+func (n *Node) markedCopy(kind DiffKind) *Node {
+	nn := n.clone(kind != Removed)
+	nn.Walk(func(node *Node) bool {
+		node.diffKind = kind
+		return true
+	})
+	return nn
+}
+
+// GetDiffKind returns how this node changed when it is part
+// of a tree returned by Diff, otherwise Unchanged
+func (n *Node) GetDiffKind() DiffKind {
+	if n == nil {
+		return Unchanged
+	}
+	return n.diffKind
+}
+
+// DrawDiff draws the tree returned by Diff(a, b) with the
+// labels and branches of added nodes in green and removed
+// nodes in red. Unchanged nodes keep their own colors.
+func DrawDiff(a, b *Node, di *DrawInput) string {
+	merged := Diff(a, b)
+	merged.Walk(func(node *Node) bool {
+		switch node.diffKind {
+		case Added:
+			node.clearColors()
+			node.SetColor(color.FgGreen).SetBranchColor(color.FgGreen)
+		case Removed:
+			node.clearColors()
+			node.SetColor(color.FgRed).SetBranchColor(color.FgRed)
+		}
+		return true
+	})
+	return merged.DrawOptions(di)
+}
+
+// DiffRender draws the tree returned by Diff(a, b) like a
+// unified diff: each row is prefixed with "+ " when its node
+// was added in b, "- " when it was removed from a and two
+// spaces otherwise. The gutter sits left of the tree and its
+// border, so rows that don't belong to a node, such as the
+// border, get spaces. Nodes keep their own colors. With
+// ChainInline only nodes that changed the same way share a
+// row, so each row has a single gutter mark.
+func DiffRender(a, b *Node, di *DrawInput) string {
+	lines, trailer := Diff(a, b).drawLines(di)
+	var out strings.Builder
+	for _, line := range lines {
+		out.WriteString(diffGutter(line.node))
+		out.WriteString(line.text)
+		out.WriteString("\n")
+	}
+	if trailer != "" {
+		for _, line := range strings.SplitAfter(trailer, "\n") {
+			if line != "" {
+				out.WriteString("  " + line)
+			}
+		}
+	}
+	return out.String()
+}
+
+// diffGutter returns the DiffRender prefix for a row of node
+func diffGutter(node *Node) string {
+	switch node.GetDiffKind() {
+	case Added:
+		return "+ "
+	case Removed:
+		return "- "
+	}
+	return "  "
+}
@@ -0,0 +1,159 @@
+package gree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func diffTrees() (a, b *Node) {
+	a = NewNode("root")
+	a.NewChild("kept").NewChild("old")
+	a.NewChild("dropped").NewChild("gone")
+	a.NewChild("last")
+	b = NewNode("root")
+	k := b.NewChild("kept")
+	k.NewChild("old")
+	k.NewChild("new")
+	b.NewChild("last")
+	b.NewChild("fresh")
+	return a, b
+}
+
+func TestDiff(t *testing.T) {
+	a, b := diffTrees()
+	merged := Diff(a, b)
+	expected := "root(kept(old, new), dropped(gone), last, fresh)"
+	if got := merged.Summary(); got != expected {
+		t.Fatalf("expected '%s', got '%s'", expected, got)
+	}
+	kinds := map[string]DiffKind{
+		"root": Unchanged, "kept": Unchanged, "old": Unchanged, "new": Added,
+		"dropped": Removed, "gone": Removed, "last": Unchanged, "fresh": Added,
+	}
+	merged.Walk(func(node *Node) bool {
+		if node.GetDiffKind() != kinds[node.String()] {
+			t.Errorf("expected '%s' to be %d, got %d", node, kinds[node.String()], node.GetDiffKind())
+		}
+		return true
+	})
+	if a.NumChildren() != 3 || b.GetChild(0).NumChildren() != 2 {
+		t.Errorf("expected a and b to be untouched")
+	}
+}
+
+func TestDrawDiff(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+	a, b := diffTrees()
+	lines := strings.Split(DrawDiff(a, b, &DrawInput{}), "\n")
+	green := color.New(color.FgGreen)
+	red := color.New(color.FgRed)
+	expected := []string{
+		"root",
+		"├── kept",
+		"│   ├── old",
+		"│   " + green.Sprint("└──") + " " + green.Sprint("new"),
+		red.Sprint("├──") + " " + red.Sprint("dropped"),
+		red.Sprint("│") + "   " + red.Sprint("└──") + " " + red.Sprint("gone"),
+		"├── last",
+		green.Sprint("└──") + " " + green.Sprint("fresh"),
+	}
+	for i, e := range expected {
+		if strings.TrimRight(lines[i], " ") != e {
+			t.Errorf("line %d, expected %q, got %q", i, e, lines[i])
+		}
+	}
+}
+
+func TestDiffRender(t *testing.T) {
+	a, b := diffTrees()
+	expected := []string{
+		"  root",
+		"  ├── kept",
+		"  │   ├── old",
+		"+ │   └── new",
+		"- ├── dropped",
+		"- │   └── gone",
+		"  ├── last",
+		"+ └── fresh",
+	}
+	got := strings.Split(strings.TrimSuffix(DiffRender(a, b, &DrawInput{}), "\n"), "\n")
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d lines, got\n%s", len(expected), strings.Join(got, "\n"))
+	}
+	for i, e := range expected {
+		if strings.TrimRight(got[i], " ") != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+		}
+	}
+	got = strings.Split(DiffRender(a, b, &DrawInput{Border: true, Debug: true}), "\n")
+	if !strings.HasPrefix(got[0], "  ┌") || !strings.HasPrefix(got[4], "+ │ │   └── new") {
+		t.Errorf("expected the gutter outside the border, got\n%s", strings.Join(got, "\n"))
+	}
+	if ruler := got[len(got)-2]; !strings.HasPrefix(ruler, "  ") {
+		t.Errorf("expected the ruler to line up with the tree, got '%s'", ruler)
+	}
+}
+
+func TestDiffRenderChained(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("x").NewChild("y")
+	b := a.Clone()
+	b.GetChild(0).GetChild(0).NewChild("z").NewChild("w")
+	expected := []string{
+		"  root/x/y",
+		"+ └── z/w",
+	}
+	got := strings.Split(strings.TrimSuffix(DiffRender(a, b, &DrawInput{ChainInline: true}), "\n"), "\n")
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d lines, got\n%s", len(expected), strings.Join(got, "\n"))
+	}
+	for i, e := range expected {
+		if strings.TrimRight(got[i], " ") != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+		}
+	}
+}
+
+func TestDiffSharedIDs(t *testing.T) {
+	a := NewNode("root")
+	c := a.NewChild("child1")
+	c.NewChild("x")
+	c.NewChild("y")
+	a.NewChild("child2")
+	b := a.Snapshot()
+	b.GetChild(0).MoveTo(b.GetChild(1))
+	ids := make(map[string]bool)
+	merged := Diff(a, b)
+	merged.Walk(func(node *Node) bool {
+		if ids[node.GetID()] {
+			t.Errorf("expected unique ids, '%s' shares one", node)
+		}
+		ids[node.GetID()] = true
+		return true
+	})
+	expected := []string{
+		"- ├── child1",
+		"- │   ├── x",
+		"- │   └── y",
+		"  └── child2",
+		"+     └── child1",
+		"+         ├── x",
+		"+         └── y",
+	}
+	for name, di := range map[string]*DrawInput{"plain": {}, "isolated": {Isolated: true}, "chained": {ChainInline: true}} {
+		got := strings.Split(DiffRender(a, b, di), "\n")[1:]
+		for i, e := range expected {
+			if strings.TrimRight(got[i], " ") != e {
+				t.Errorf("%s line %d, expected '%s', got '%s'", name, i, e, got[i])
+			}
+		}
+	}
+	rows := merged.RenderRowIndex(&DrawInput{Isolated: true})
+	if rows[1].GetDiffKind() != Removed || rows[5].GetDiffKind() != Added {
+		t.Errorf("expected rows to map to nodes of their own kind, got %v", rows)
+	}
+}
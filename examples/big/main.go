@@ -45,10 +45,6 @@ func main() {
 	a.GetGeneration(2)[0].NewChild("bob")
 
 	// use every custom input option
-	di := gree.DrawInput{
-		Border:  true,
-		Padding: "  ",
-		Debug:   true,
-	}
-	fmt.Println(a.DrawOptions(&di))
+	di := gree.DefaultDrawInput().WithBorder().WithPadding("  ").WithDebug()
+	fmt.Println(a.DrawOptions(di))
 }
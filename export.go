@@ -0,0 +1,322 @@
+package gree
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// summaryEscaper escapes the characters Summary uses for structure
+var summaryEscaper = strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`, `,`, `\,`)
+
+// Summary returns a single line representation of this node
+// and its descendents, e.g. "root(child1, child2(grandchild1))".
+// Children are wrapped in parentheses and separated by ", ".
+// Backslashes, parentheses and commas in contents are escaped
+// with a backslash so the result can be read by ParseSummary.
+func (n *Node) Summary() string {
+	var b strings.Builder
+	n.summary(&b)
+	return b.String()
+}
+
+func (n *Node) summary(b *strings.Builder) {
+	b.WriteString(summaryEscaper.Replace(n.contents))
+	if len(n.children) == 0 {
+		return
+	}
+	b.WriteString("(")
+	for i, child := range n.children {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		child.summary(b)
+	}
+	b.WriteString(")")
+}
+
+// ParseSummary builds a tree from the output of Summary
+func ParseSummary(s string) (*Node, error) {
+	p := summaryParser{input: []rune(s)}
+	n, err := p.parseNode()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.input) {
+		return nil, fmt.Errorf("unexpected '%c' at position %d", p.input[p.pos], p.pos)
+	}
+	return n, nil
+}
+
+type summaryParser struct {
+	input []rune
+	pos   int
+}
+
+func (p *summaryParser) parseNode() (*Node, error) {
+	var contents []rune
+	for p.pos < len(p.input) {
+		r := p.input[p.pos]
+		if r == '\\' {
+			if p.pos+1 == len(p.input) {
+				return nil, errors.New("summary ends with an unfinished escape")
+			}
+			contents = append(contents, p.input[p.pos+1])
+			p.pos += 2
+			continue
+		}
+		if r == '(' || r == ')' || r == ',' {
+			break
+		}
+		contents = append(contents, r)
+		p.pos++
+	}
+	n := NewNode(string(contents))
+	if p.pos == len(p.input) || p.input[p.pos] != '(' {
+		return n, nil
+	}
+	p.pos++ // opening parenthesis
+	for {
+		child, err := p.parseNode()
+		if err != nil {
+			return nil, err
+		}
+		n.AddChild(child)
+		if p.pos == len(p.input) {
+			return nil, errors.New("summary is missing a closing parenthesis")
+		}
+		switch p.input[p.pos] {
+		case ')':
+			p.pos++
+			return n, nil
+		case ',':
+			p.pos++
+			if p.pos < len(p.input) && p.input[p.pos] == ' ' {
+				p.pos++
+			}
+		default:
+			return nil, fmt.Errorf("unexpected '%c' at position %d", p.input[p.pos], p.pos)
+		}
+	}
+}
+
+// jsonNode is the serialized form of a Node. Fields are
+// always written in this order.
+type jsonNode struct {
+	ID       string              `json:"id"`
+	Contents string              `json:"contents"`
+	Colors   [][]color.Attribute `json:"colors,omitempty"`
+	Meta     string              `json:"meta,omitempty"`
+	Status   Status              `json:"status,omitempty"`
+	Data     interface{}         `json:"data,omitempty"`
+	Children []*jsonNode         `json:"children,omitempty"`
+}
+
+func (n *Node) toJSONNode() *jsonNode {
+	jn := &jsonNode{
+		ID:       n.GetID(),
+		Contents: n.contents,
+		Colors:   n.colorsApplied,
+		Meta:     n.meta,
+		Status:   n.status,
+		Data:     n.data,
+	}
+	for _, child := range n.children {
+		jn.Children = append(jn.Children, child.toJSONNode())
+	}
+	return jn
+}
+
+func (jn *jsonNode) toNode() *Node {
+	n := NewNode(jn.Contents)
+	if jn.ID != "" {
+		n.id = jn.ID
+	}
+	for _, attrs := range jn.Colors {
+		n.SetColors(attrs...)
+	}
+	n.meta = jn.Meta
+	n.status = jn.Status
+	n.data = jn.Data
+	for _, child := range jn.Children {
+		n.AddChild(child.toNode())
+	}
+	return n
+}
+
+// MarshalJSON encodes this node and its descendents including
+// ids, contents, colors, meta, status and data
+func (n *Node) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.toJSONNode())
+}
+
+// UnmarshalJSON replaces this node's contents and children
+// with the decoded tree, keeping the encoded ids. Data is
+// decoded into the generic encoding/json types. The old
+// children are detached with RemoveChild and the new ones
+// added with AddChild, so a journal records the change.
+func (n *Node) UnmarshalJSON(b []byte) error {
+	var jn jsonNode
+	if err := json.Unmarshal(b, &jn); err != nil {
+		return err
+	}
+	decoded := jn.toNode()
+	for len(n.children) > 0 {
+		n.RemoveChild(len(n.children) - 1)
+	}
+	n.id = decoded.id
+	n.SetContents(decoded.contents)
+	n.colored = decoded.colored
+	n.contentsColored = decoded.contentsColored
+	n.colorsApplied = decoded.colorsApplied
+	n.meta = decoded.meta
+	n.status = decoded.status
+	n.data = decoded.data
+	for len(decoded.children) > 0 {
+		n.AddChild(decoded.removeChildAt(0))
+	}
+	if n.padding == "" {
+		n.SetPadding(defaultPadding)
+	}
+	return nil
+}
+
+// ToJSONIndent encodes this node like MarshalJSON but indented
+// with indent. Fields are always written in the same order,
+// children in tree order and map keys sorted, so the output
+// is stable enough for golden files and diffs.
+func (n *Node) ToJSONIndent(indent string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", indent)
+	if err := enc.Encode(n.toJSONNode()); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// xmlNode is the serialized XML form of a Node
+type xmlNode struct {
+	XMLName  xml.Name   `xml:"node"`
+	ID       string     `xml:"id,attr,omitempty"`
+	Contents string     `xml:"contents,attr"`
+	Color    string     `xml:"color,attr,omitempty"`
+	Meta     string     `xml:"meta,attr,omitempty"`
+	Status   Status     `xml:"status,attr,omitempty"`
+	Children []*xmlNode `xml:"node"`
+}
+
+// formatColors encodes color groups as "31,1;44", one group
+// per SetColors call
+func formatColors(groups [][]color.Attribute) string {
+	var parts []string
+	for _, group := range groups {
+		var attrs []string
+		for _, attr := range group {
+			attrs = append(attrs, strconv.Itoa(int(attr)))
+		}
+		parts = append(parts, strings.Join(attrs, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// parseColors decodes the format written by formatColors
+func parseColors(s string) ([][]color.Attribute, error) {
+	var groups [][]color.Attribute
+	if s == "" {
+		return groups, nil
+	}
+	for _, part := range strings.Split(s, ";") {
+		var group []color.Attribute
+		for _, attr := range strings.Split(part, ",") {
+			i, err := strconv.Atoi(strings.TrimSpace(attr))
+			if err != nil {
+				return nil, fmt.Errorf("invalid color '%s'", attr)
+			}
+			group = append(group, color.Attribute(i))
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func (n *Node) toXMLNode() *xmlNode {
+	xn := &xmlNode{
+		ID:       n.GetID(),
+		Contents: n.contents,
+		Color:    formatColors(n.colorsApplied),
+		Meta:     n.meta,
+		Status:   n.status,
+	}
+	for _, child := range n.children {
+		xn.Children = append(xn.Children, child.toXMLNode())
+	}
+	return xn
+}
+
+func (xn *xmlNode) toNode() (*Node, error) {
+	n := NewNode(xn.Contents)
+	if xn.ID != "" {
+		n.id = xn.ID
+	}
+	groups, err := parseColors(xn.Color)
+	if err != nil {
+		return nil, err
+	}
+	for _, attrs := range groups {
+		n.SetColors(attrs...)
+	}
+	n.meta = xn.Meta
+	n.status = xn.Status
+	for _, child := range xn.Children {
+		nc, err := child.toNode()
+		if err != nil {
+			return nil, err
+		}
+		n.AddChild(nc)
+	}
+	return n, nil
+}
+
+// ToXML encodes this node and its descendents as nested
+// <node> elements, e.g. <node contents="root"><node
+// contents="child1"></node></node>. Ids, colors, meta and
+// status are written as attributes. Data is not included.
+func (n *Node) ToXML() ([]byte, error) {
+	return xml.Marshal(n.toXMLNode())
+}
+
+// FromXML builds a tree from the format written by ToXML
+func FromXML(b []byte) (*Node, error) {
+	var xn xmlNode
+	if err := xml.Unmarshal(b, &xn); err != nil {
+		return nil, err
+	}
+	return xn.toNode()
+}
+
+// ToTaskList returns this node and its descendents as a
+// GitHub flavored Markdown task list nested by depth. Nodes
+// with the Success status are checked, all others unchecked.
+func (n *Node) ToTaskList() string {
+	var b strings.Builder
+	n.taskList(&b, 0)
+	return b.String()
+}
+
+func (n *Node) taskList(b *strings.Builder, depth int) {
+	check := " "
+	if n.status == Success {
+		check = "x"
+	}
+	fmt.Fprintf(b, "%s- [%s] %s\n", strings.Repeat("  ", depth), check, n.contents)
+	for _, child := range n.children {
+		child.taskList(b, depth+1)
+	}
+}
@@ -0,0 +1,167 @@
+package gree
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestSummary(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1")
+	a.NewChild("child2")
+	a.NewChild("child3").NewChild("grandchild1")
+	expected := "root(child1, child2, child3(grandchild1))"
+	if got := a.Summary(); got != expected {
+		t.Errorf("expected '%s', got '%s'", expected, got)
+	}
+}
+
+func TestParseSummary(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("f(x), g").NewChild(` back\slash`)
+	a.NewChild("child2")
+	parsed, err := ParseSummary(a.Summary())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if parsed.Draw() != a.Draw() {
+		t.Errorf("expected round trip to match, got\n%s\nexpected\n%s", parsed.Draw(), a.Draw())
+	}
+	for _, bad := range []string{"root(child1", "root(child1))", `root\`} {
+		if _, err := ParseSummary(bad); err == nil {
+			t.Errorf("expected error parsing '%s'", bad)
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1").SetColorRed().SetMeta("4K").NewChild("grandchild1")
+	a.NewChild("child2").SetStatus(Success).SetData(map[string]interface{}{"size": 1.5})
+	b, err := json.Marshal(a)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var decoded Node
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if decoded.Draw() != a.Draw() {
+		t.Errorf("expected decoded tree to draw the same, got\n%s", decoded.Draw())
+	}
+	if decoded.GetID() != a.GetID() || decoded.GetChild(1).GetStatus() != Success {
+		t.Errorf("expected ids and statuses to be kept")
+	}
+	if got := decoded.GetChild(1).GetData().(map[string]interface{})["size"]; got != 1.5 {
+		t.Errorf("expected data to be kept, got %v", got)
+	}
+}
+
+func TestUnmarshalJSONReplacesChildren(t *testing.T) {
+	a := NewNode("old")
+	a.EnableJournal()
+	old := a.NewChild("child1")
+	b, err := json.Marshal(NewNode("new").Add("x", "y"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := json.Unmarshal(b, a); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if old.parent != nil || a.Summary() != "new(x, y)" || a.GetChild(0).parent != a {
+		t.Errorf("expected the old children to be detached, got %s", a.Summary())
+	}
+	for i := 0; i < 4; i++ {
+		if err := a.Undo(); err != nil {
+			t.Fatalf("unexpected error on undo %d: %s", i, err)
+		}
+	}
+	if a.Summary() != "old(child1)" || a.GetChild(0) != old {
+		t.Errorf("expected the journal to restore the old tree, got %s", a.Summary())
+	}
+}
+
+func TestToJSONIndent(t *testing.T) {
+	var next int
+	SetIDGenerator(func() string {
+		next++
+		return fmt.Sprintf("node-%d", next)
+	})
+	defer SetIDGenerator(nil)
+	a := NewNode("root")
+	a.NewChild("child1").SetData(map[string]int{"b": 2, "a": 1})
+	got, err := a.ToJSONIndent("  ")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := `{
+  "id": "node-1",
+  "contents": "root",
+  "children": [
+    {
+      "id": "node-2",
+      "contents": "child1",
+      "data": {
+        "a": 1,
+        "b": 2
+      }
+    }
+  ]
+}`
+	if string(got) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, string(got))
+	}
+}
+
+func TestXMLRoundTrip(t *testing.T) {
+	a := NewNode(`root "quoted" <&>`)
+	a.NewChild("child1").SetColors(color.FgRed, color.Bold).SetColors(color.BgBlue).NewChild("grandchild1")
+	a.NewChild("child2").SetMeta("4K").SetStatus(Failure)
+	b, err := a.ToXML()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !strings.Contains(string(b), `contents="root &#34;quoted&#34; &lt;&amp;&gt;"`) {
+		t.Errorf("expected escaped contents attribute, got %s", string(b))
+	}
+	if !strings.Contains(string(b), `color="31,1;44"`) {
+		t.Errorf("expected color attribute, got %s", string(b))
+	}
+	decoded, err := FromXML(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if decoded.Draw() != a.Draw() {
+		t.Errorf("expected decoded tree to draw the same, got\n%s", decoded.Draw())
+	}
+	if decoded.GetChild(1).GetStatus() != Failure {
+		t.Errorf("expected status to be kept")
+	}
+}
+
+func TestFromXMLInvalidColor(t *testing.T) {
+	if _, err := FromXML([]byte(`<node contents="a" color="red"></node>`)); err == nil {
+		t.Errorf("expected error for invalid color")
+	}
+}
+
+func TestToTaskList(t *testing.T) {
+	a := NewNode("release")
+	b := a.NewChild("build").SetStatus(Success)
+	b.NewChild("linux").SetStatus(Success)
+	b.NewChild("darwin").SetStatus(Failure)
+	a.NewChild("publish").SetStatus(Pending)
+	expected := `- [ ] release
+  - [x] build
+    - [x] linux
+    - [ ] darwin
+  - [ ] publish
+`
+	if got := a.ToTaskList(); got != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, got)
+	}
+}
@@ -0,0 +1,160 @@
+package gree
+
+import (
+	"fmt"
+)
+
+// footnote is a subtree moved out of the drawing by a
+// MaxWidth split
+type footnote struct {
+	cut      *Node  // node whose children were moved
+	block    *Node  // new root holding the moved children
+	contents string // contents of cut before it was marked
+}
+
+// drawFootnoted draws a copy of the tree split into blocks
+// that each fit within di.MaxWidth. The lines are mapped
+// back to the nodes of this tree through their ids.
+func (n *Node) drawFootnoted(di *DrawInput) (lines []drawnLine, trailer string) {
+	view := n.linkedClone()
+	if di.MaxDepth > 0 {
+		view.pruneBelow(di.MaxDepth)
+	}
+	subs := func(i int) *DrawInput {
+		sub := *di
+		sub.MaxDepth = 0
+		sub.Legend = nil
+		sub.Debug = false
+		sub.footnoted = true
+		sub.ViewportHeight = 0
+		if i > 0 {
+			sub.MinDepth = 0
+			sub.ShowParentStub = false
+			sub.RootLabel = ""
+		}
+		return &sub
+	}
+	var notes []*footnote
+	blocks := []*Node{view}
+	for i := 0; i < len(blocks); i++ {
+		for _, note := range blocks[i].splitForWidth(subs(i), len(notes)+1) {
+			notes = append(notes, note)
+			blocks = append(blocks, note.block)
+		}
+	}
+	// number the footnotes in the order they are referenced
+	// when reading from the top down
+	byCut := make(map[*Node]*footnote)
+	for _, note := range notes {
+		byCut[note.cut] = note
+	}
+	blocks = []*Node{view}
+	for i := 0; i < len(blocks); i++ {
+		for _, node := range blocks[i].drawnDescendents(true) {
+			if note, ok := byCut[node]; ok {
+				blocks = append(blocks, note.block)
+				note.mark(len(blocks) - 1)
+			}
+		}
+	}
+	for i, block := range blocks {
+		if i > 0 {
+			lines = append(lines, drawnLine{})
+		}
+		blockLines, _ := block.drawLines(subs(i))
+		lines = append(lines, blockLines...)
+	}
+	toOriginals(lines)
+	top, bottom := di.viewport(len(lines))
+	lines = lines[top:bottom]
+	if len(di.Legend) > 0 {
+		trailer += drawLegend(di.Legend)
+	}
+	if di.Debug {
+		trailer += drawRuler(di.lastColumn(di.MaxWidth), di.RulerInterval)
+	}
+	return lines, trailer
+}
+
+// mark labels the cut node and the block root with number
+func (f *footnote) mark(number int) {
+	f.cut.contents = fmt.Sprintf("%s [%d]", f.contents, number)
+	f.block.contents = fmt.Sprintf("[%d] %s", number, f.contents)
+}
+
+// splitForWidth moves the children of nodes whose subtrees
+// don't fit in di.MaxWidth into new roots, provisionally
+// numbering them from first so the markers take up room
+// in the layout.
+func (n *Node) splitForWidth(di *DrawInput, first int) (notes []*footnote) {
+	for {
+		n.layoutWidth(di)
+		cut := n.findCut(di)
+		if cut == nil {
+			return notes
+		}
+		note := &footnote{
+			cut:      cut,
+			block:    cut.copyNode(true),
+			contents: cut.contents,
+		}
+		// the block root stands for the same node as cut
+		note.block.source = cut.source
+		note.block.footnoteRoot = true
+		order := cut.drawOrder
+		for len(cut.children) > 0 {
+			note.block.insertChild(len(note.block.children), cut.removeChildAt(0))
+		}
+		note.block.drawOrder = order
+		note.mark(first + len(notes))
+		notes = append(notes, note)
+	}
+}
+
+// findCut returns the parent of the first visible node whose
+// row doesn't fit in di.MaxWidth, skipping nodes whose parent
+// is drawn as a root since moving all of a root's children
+// wouldn't make the drawing any narrower. Must be called
+// after relate.
+func (n *Node) findCut(di *DrawInput) *Node {
+	extra := n.extraWidth(di)
+	for _, desc := range n.drawnDescendents(false) {
+		if !desc.visible(di) || desc.isRoot {
+			continue
+		}
+		if desc.rowWidth()+extra+1 <= di.MaxWidth {
+			continue
+		}
+		if p := desc.parent; !p.isRoot && !p.hidden {
+			return p
+		}
+	}
+	return nil
+}
+
+// drawnDescendents returns the descendents of this node in
+// the order they are drawn, following SetDrawOrder, with this
+// node first when self is set
+func (n *Node) drawnDescendents(self bool) (all []*Node) {
+	if self {
+		all = append(all, n)
+	}
+	for _, child := range n.orderedChildren() {
+		all = append(all, child.drawnDescendents(true)...)
+	}
+	return all
+}
+
+// pruneBelow removes all descendents more than depth
+// generations beneath this node
+func (n *Node) pruneBelow(depth int) {
+	if depth == 0 {
+		for len(n.children) > 0 {
+			n.removeChildAt(0)
+		}
+		return
+	}
+	for _, child := range n.children {
+		child.pruneBelow(depth - 1)
+	}
+}
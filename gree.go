@@ -29,6 +29,16 @@
 //
 // Any node from which the Draw*() methods are called
 // will be considered the root node for display purposes.
+//
+// The methods that navigate or query the tree (GetChild,
+// GetGeneration, NumChildren, Height, Walk, etc.) can be
+// called on a nil *Node and return nil or the zero value,
+// so a chain like a.GetChild(5).GetChild(0) yields nil
+// instead of panicking when a child is missing. NewChild and
+// AddChild on a nil *Node add nothing and return nil.
+//
+// A node can't be added beneath itself: AddChild returns nil
+// and TryAddChild and MoveTo return ErrCycle instead.
 package gree
 
 import (
@@ -50,20 +60,43 @@ type Node struct {
 	parent   *Node
 	lineage  []*Node // lineage is the parent and all of the parent's parents
 	children []*Node
-	id       uuid.UUID
+	id       string
+	journal  *journal // only set on nodes where EnableJournal was called
+	source   *Node    // node a copy made with linkedCopy stands for
 
 	// Contents is the string identifier for thise node
 	// and is what will be displayed
 	contents         string
 	contentsTrimmed  string
+	label            string // uncolored text rendered for the current draw
+	labelPad         int    // trailing spaces added to label by PadSiblingsToMax
+	marked           bool   // children hang from DrawInput.ParentBranchMark for the current draw
+	status           Status
 	contentsColored  string
 	colored          bool
-	colorsApplied    []color.Attribute
+	colorsApplied    [][]color.Attribute // each SetColor* call adds a group
+	colorRanges      []colorRange        // set by SetColorRange
+	branchColor      color.Attribute
+	meta             string // rendered right-aligned with DrawInput.MetaColumn
+	data             interface{}
+	diffKind         DiffKind // set on the nodes of a Diff tree
+	tags             map[string]bool
+	raw              string // set by SetContentsRaw
+	rawWidth         int
+	rawContents      string // contents when raw was set
+	rawLabel         bool   // label holds room for raw in the current draw
+	branchColored    bool
 	contentFontWidth int
 	contentLength    int
 	// Padding determines how many spaces for
 	// each indentation, defaults to "   " (3 spaces)
 	padding             string
+	drawPadding         string // padding used for the current draw
+	decorator           string // connector rendered before the label for the current draw
+	branchDecorator     string // set by SetDecorator
+	lastDecorator       string // set by SetDecorator
+	edgeLabel           string // set by SetEdgeLabel
+	drawOrder           []int  // set by SetDrawOrder
 	depth               int
 	amLastSibling       bool
 	amSibling           bool
@@ -71,6 +104,11 @@ type Node struct {
 	parentIsLastSibling bool
 	parentIsRoot        bool
 	isRoot              bool
+	hidden              bool // above DrawInput.MinDepth for the current draw
+	footnoteRoot        bool // root of a MaxWidth block, drawn without an index
+	summarized          bool // counts added by SummarizeDeeperThan, drawn undecorated
+	pruned              bool // dropped by DrawInput.PruneEmpty for the current draw
+	rdepth              int  // depth relative to the node being drawn
 	x1                  int
 	x2                  int
 	done                bool
@@ -92,19 +130,54 @@ func (c *counter) get() int {
 	return c.count
 }
 
-// GetID returns the UUID of the node.
-// Useful for identifying unique nodes when
-// many have the same contents.
+// GetID returns the UUID of the node (or the id from
+// the generator passed to SetIDGenerator). Useful for identifying unique nodes when
+// many have the same contents. A node made as a literal
+// (e.g., &Node{}) gets its id on first use.
 func (n *Node) GetID() string {
-	return n.id.String()
+	if n == nil {
+		return ""
+	}
+	n.ensureInit()
+	return n.id
+}
+
+// ensureInit gives a node made as a literal instead of with
+// NewNode the id and padding NewNode would have given it
+func (n *Node) ensureInit() {
+	if n.id == "" {
+		n.id = newID()
+	}
+	if n.padding == "" {
+		n.padding = extendPadding(defaultPadding)
+	}
+}
+
+// idGenerator produces ids for new nodes when set
+// via SetIDGenerator, otherwise random UUIDs are used
+var idGenerator func() string
+
+// SetIDGenerator replaces the function used to generate
+// ids for new nodes. This is mainly useful for tests that
+// need deterministic ids, e.g. for golden files of serialized
+// output. Passing nil restores the default random UUIDs.
+func SetIDGenerator(gen func() string) {
+	idGenerator = gen
+}
+
+func newID() string {
+	if idGenerator != nil {
+		return idGenerator()
+	}
+	return uuid.New().String()
 }
 
 // setx1 sets the x1 property of this node and auto
 // recalculates x2 based on the contents
 func (n *Node) setx1(x int) {
 	n.x1 = x
-	n.x2 = n.x1 + utf8.RuneCountInString(n.contents)
-	n.contentLength = utf8.RuneCountInString(n.contents)
+	n.x2 = n.x1 + VisibleWidth(n.label)
+	n.contentLength = VisibleWidth(n.label)
 }
 
 // SetColorMagenta sets the color of the node to magenta
@@ -125,45 +198,198 @@ func (n *Node) SetColorRed() *Node {
 // SetColor sets the color of the node to the passed fatih/color attribute
 // Requires that the caller import fatih/color and reference their color.Attribute
 func (n *Node) SetColor(fatihcolor color.Attribute) *Node {
+	return n.SetColors(fatihcolor)
+}
+
+// SetColors applies all of the passed fatih/color attributes to the
+// node at once (e.g., a foreground and a background) so the contents
+// are wrapped in a single escape sequence rather than one per call.
+func (n *Node) SetColors(attrs ...color.Attribute) *Node {
+	if len(attrs) == 0 {
+		return n
+	}
 	if n.contentsColored == "" {
 		n.contentsColored = n.contents
 	}
-	n.contentsColored = color.New(fatihcolor).Sprint(n.contentsColored)
+	n.contentsColored = color.New(attrs...).Sprint(n.contentsColored)
 	n.colored = true
-	n.colorsApplied = append(n.colorsApplied, fatihcolor)
+	n.colorsApplied = append(n.colorsApplied, attrs)
+	return n
+}
+
+// ColorWhere applies the passed fatih/color attribute to this
+// node and every descendent for which pred returns true. It
+// returns the number of nodes colored.
+func (n *Node) ColorWhere(pred func(*Node) bool, attr color.Attribute) (count int) {
+	if n == nil {
+		return 0
+	}
+	for _, node := range append([]*Node{n}, n.GetAllDescendents()...) {
+		if pred(node) {
+			node.SetColor(attr)
+			count++
+		}
+	}
+	return count
+}
+
+// colorRange is a color applied to part of the contents
+type colorRange struct {
+	start, end int
+	attr       color.Attribute
+}
+
+// SetColorRange colors the runes of the contents from start
+// up to but not including end with attr, on top of the colors
+// of the whole label, e.g. SetColorRange(8, 10, color.FgGreen)
+// on "status: OK" colors just "OK". It can be called more
+// than once to color several ranges. Ranges past the end of
+// the contents are cut short and empty ones are ignored.
+func (n *Node) SetColorRange(start, end int, attr color.Attribute) *Node {
+	if start < 0 {
+		start = 0
+	}
+	if end <= start {
+		return n
+	}
+	n.colorRanges = append(n.colorRanges, colorRange{start, end, attr})
+	return n
+}
+
+// applyColorRanges adds the SetColorRange colors to the cells
+// of the contents in row, up to but not including column limit
+func (n *Node) applyColorRanges(row *rrow, limit int, di *DrawInput) {
+	if len(n.colorRanges) == 0 || (n.rdepth == 0 && di.RootLabel != "") {
+		return
+	}
+	// the contents come last in the label but for the suffix
+	col := n.labelColumn() + VisibleWidth(n.label) - n.labelPad - VisibleWidth(n.suffixText(di)) - VisibleWidth(n.contents)
+	for i, r := range []rune(n.contents) {
+		for _, cr := range n.colorRanges {
+			if i >= cr.start && i < cr.end && col < limit {
+				attrs := row.colors[col]
+				row.setColorI(col, append(attrs[:len(attrs):len(attrs)], cr.attr)...)
+			}
+		}
+		col += runeWidth(r)
+	}
+}
+
+// clearColors removes the colors set with the SetColor*
+// methods
+func (n *Node) clearColors() {
+	n.colorsApplied = nil
+	n.colorRanges = nil
+	n.colored = false
+	n.contentsColored = ""
+}
+
+// SetBranchColor sets the color of the connector lines drawn
+// for this node and its descendents. A descendent's own branch
+// color takes precedence over the one inherited from an ancestor.
+func (n *Node) SetBranchColor(attr color.Attribute) *Node {
+	n.branchColor = attr
+	n.branchColored = true
 	return n
 }
 
-func (n *Node) reColor() string {
+// effectiveBranchColor returns the branch color of the last
+// node in chain that has one set, searching backwards
+func effectiveBranchColor(chain []*Node) (attr color.Attribute, ok bool) {
+	for i := len(chain) - 1; i >= 0; i-- {
+		if chain[i].branchColored {
+			return chain[i].branchColor, true
+		}
+	}
+	return attr, false
+}
+
+func (n *Node) reColor(groups [][]color.Attribute) string {
 	tstring := n.contentsTrimmed
-	for _, colour := range n.colorsApplied {
-		tstring = color.New(colour).Sprint(tstring)
+	for _, colours := range groups {
+		tstring = color.New(colours...).Sprint(tstring)
 	}
 	return tstring
 }
 
+// consoleSize returns the columns and rows of the terminal
+// stdout is connected to, or zeros when it isn't a terminal.
+// Tests replace it to draw as if on a terminal.
+var consoleSize = consolesize.GetConsoleSize
+
 func (n *Node) setTerminalDimensions() {
-	n.terminalWidth, n.terminalHeight = consolesize.GetConsoleSize()
+	n.terminalWidth, n.terminalHeight = consoleSize()
 }
 
-type collector struct {
-	results []*Node
+// ResetLayout clears the scratch state left on this node and
+// its descendents by previous draws (positions, lineage, sibling
+// flags and indexes). Draws don't need it but it gives a clean
+// slate when inspecting nodes or reusing them across different
+// DrawInput configurations.
+func (n *Node) ResetLayout() {
+	n.resetRenderState()
+	for _, desc := range n.GetAllDescendents() {
+		desc.resetRenderState()
+	}
 }
 
-func (c *collector) add(n *Node) {
-	c.results = append(c.results, n)
+func (n *Node) resetRenderState() {
+	n.lineage = nil
+	n.contentsTrimmed = ""
+	n.label = ""
+	n.labelPad = 0
+	n.marked = false
+	n.rawLabel = false
+	n.drawPadding = ""
+	n.decorator = ""
+	n.contentLength = 0
+	n.amLastSibling = false
+	n.amSibling = false
+	n.parentIsSibling = false
+	n.parentIsLastSibling = false
+	n.parentIsRoot = false
+	n.isRoot = false
+	n.hidden = false
+	n.pruned = false
+	n.rdepth = 0
+	n.x1 = 0
+	n.x2 = 0
+	n.done = false
+	n.index = 0
+	n.count = counter{}
 }
 
 // GetDepth returns this node's depth. Depths are updated
 // as nodes are added.
 func (n *Node) GetDepth() int {
+	if n == nil {
+		return 0
+	}
 	return n.depth
 }
 
+// DepthRelativeTo returns the number of edges from ancestor
+// down to this node and whether ancestor is actually on this
+// node's path to the root. A node is considered to be at
+// depth 0 relative to itself.
+func (n *Node) DepthRelativeTo(ancestor *Node) (int, bool) {
+	depth := 0
+	for p := n; p != nil; p = p.parent {
+		if p == ancestor {
+			return depth, true
+		}
+		depth++
+	}
+	return 0, false
+}
+
 // GetChild returns a pointer to the y'th child
 // of the Node. If the y'th child does not exist
 // a nil pointer is returned.
 func (n *Node) GetChild(y int) (dc *Node) {
+	if n == nil {
+		return nil
+	}
 	for i, c := range n.children {
 		if y == i {
 			return c
@@ -172,36 +398,233 @@ func (n *Node) GetChild(y int) (dc *Node) {
 	return nil
 }
 
-func (n *Node) relateAsRoot() {
-	n.isRoot = true
-	n.relate(&n.count, false, true, false, false, n)
+// SetDrawOrder sets the order the children of this Node are
+// drawn in without reordering them, as the indexes of the
+// children (see GetChild) in the order to draw them, e.g.
+// []int{2, 0, 1} draws the last child first. Everything else,
+// GetChild, Walk, GetAllDescendents and the exporters included,
+// keeps using the real order. An error is returned and nothing is changed when
+// order isn't a permutation of the child indexes. Passing nil
+// restores the real order, which is also used once children
+// are added, removed or sorted.
+func (n *Node) SetDrawOrder(order []int) error {
+	if order == nil {
+		n.drawOrder = nil
+		return nil
+	}
+	if len(order) != len(n.children) {
+		return fmt.Errorf("draw order has %d indexes but there are %d children", len(order), len(n.children))
+	}
+	seen := make(map[int]bool, len(order))
+	for _, i := range order {
+		if i < 0 || i >= len(n.children) || seen[i] {
+			return fmt.Errorf("draw order must use each child index once, got %v", order)
+		}
+		seen[i] = true
+	}
+	n.drawOrder = append([]int(nil), order...)
+	return nil
+}
+
+// orderedChildren returns the children of this Node in the
+// order they are drawn
+func (n *Node) orderedChildren() []*Node {
+	if len(n.drawOrder) != len(n.children) {
+		return n.children
+	}
+	ordered := make([]*Node, len(n.children))
+	for i, c := range n.drawOrder {
+		ordered[i] = n.children[c]
+	}
+	return ordered
+}
+
+// GetChildIndex returns the position of child among the
+// children of this Node, the y to pass to GetChild or
+// RemoveChild, and whether it is a child at all. Children
+// are compared by pointer so equal contents don't matter.
+func (n *Node) GetChildIndex(child *Node) (int, bool) {
+	if n == nil {
+		return -1, false
+	}
+	i := n.childIndex(child)
+	return i, i >= 0
+}
+
+// relateAsRoot relates the tree with this node as root. The
+// counter and root lineage start fresh so that repeated draws
+// assign the same indexes.
+func (n *Node) relateAsRoot(di *DrawInput) {
+	n.count = counter{}
+	n.lineage = nil
+	// the root is its own parent in relate so start it from the
+	// left margin, otherwise a previous border shift carries over
+	n.setx1(0)
+	n.relate(di, &n.count, false, true, false, false, n, 0)
 }
 
-func (n *Node) getDescMaxWidth() (max int) {
+// visible reports whether this node produces a row for
+// the depth window of the current draw
+func (n *Node) visible(di *DrawInput) bool {
+	if n.hidden || n.pruned {
+		return false
+	}
+	return di.MaxDepth == 0 || n.rdepth <= di.MaxDepth
+}
+
+func (n *Node) getDescMaxWidth(di *DrawInput) (max int) {
 	// first have to relate before getDescMaxWidth works properly, yuck
-	n.relateAsRoot()
-	all := n.GetAllDescendents()
+	n.relateAsRoot(di)
+	all := append([]*Node{n}, n.GetAllDescendents()...)
 	for _, dec := range all {
-		declen := dec.x2 + utf8.RuneCountInString(dec.padding)
-		if declen > max {
+		if !dec.visible(di) {
+			continue
+		}
+		if declen := dec.rowWidth(); declen > max {
 			max = declen
 		}
 	}
 	return max
 }
 
+// rowWidth returns the last column this node's row needs
+// for its decorator and label, must be called after relate
+func (n *Node) rowWidth() int {
+	if n.isRoot {
+		// roots have no decorator
+		return n.x2
+	}
+	return n.labelColumn() + n.contentLength - 1
+}
+
+// labelColumn returns the column the label starts at,
+// which is also where children's connectors hang from.
+// Must be called after relate.
+func (n *Node) labelColumn() int {
+	return n.x1 + VisibleWidth(n.decorator)
+}
+
+// layoutWidth relates the tree and returns the last column
+// of the drawing, including the meta column and border
+func (n *Node) layoutWidth(di *DrawInput) int {
+	width := n.getDescMaxWidth(di)
+	width += n.extraWidth(di)
+	return width
+}
+
+// extraWidth returns the columns the meta column and
+// border add past the widest row
+func (n *Node) extraWidth(di *DrawInput) (extra int) {
+	if di.MetaColumn {
+		if metaWidth := n.maxMetaWidth(di); metaWidth > 0 {
+			// leave at least one space between tree and meta
+			extra += metaWidth + 1
+		}
+	}
+	if di.Border {
+		extra += 3
+	}
+	return extra
+}
+
 // NewNode returns a new node with contents of
 // the passed string. Please do not use color formatted
 // strings and instead use the provided SetColor* methods.
 func NewNode(contents string) *Node {
 	n := Node{
-		id: uuid.New(),
+		id: newID(),
 	}
 	n.SetContents(contents)
-	n.setPadding("   ")
+	n.SetPadding(defaultPadding)
 	return &n
 }
 
+// Clone returns a deep copy of this node and its descendents
+// with contents, colors, padding and meta preserved. Every
+// copied node gets a new id and the copy has no parent.
+func (n *Node) Clone() *Node {
+	return n.clone(false)
+}
+
+// CloneKeepIDs is like Clone but each copied node keeps the id
+// of the node it was copied from. Use this for serialization
+// round trips or restoring nodes, and be careful keeping both
+// trees around since lookups by id can no longer tell them apart.
+func (n *Node) CloneKeepIDs() *Node {
+	return n.clone(true)
+}
+
+// Snapshot returns a copy of this node and its descendents,
+// keeping ids, for drawing on another goroutine while this
+// tree goes on being edited. Take the snapshot on the goroutine
+// that edits the tree. The snapshot is meant to be read only:
+// it shares Data values with this tree, and drawing it from
+// more than one goroutine at once also needs DrawInput.Isolated.
+func (n *Node) Snapshot() *Node {
+	return n.clone(true)
+}
+
+func (n *Node) clone(keepIDs bool) *Node {
+	nn := n.copyNode(keepIDs)
+	for _, child := range n.children {
+		nn.insertChild(len(nn.children), child.clone(keepIDs))
+	}
+	nn.drawOrder = append([]int(nil), n.drawOrder...)
+	return nn
+}
+
+// linkedClone is like CloneKeepIDs but each copy points back
+// to the node it copies, for drawing the copy in place of this
+// tree (see drawCopy)
+func (n *Node) linkedClone() *Node {
+	nn := n.linkedCopy()
+	for _, child := range n.children {
+		nn.insertChild(len(nn.children), child.linkedClone())
+	}
+	nn.drawOrder = append([]int(nil), n.drawOrder...)
+	return nn
+}
+
+// linkedCopy copies this node without its children, keeping
+// a pointer back to it so a drawing of the copy can be mapped
+// back to this node whatever the ids in the tree
+func (n *Node) linkedCopy() *Node {
+	nn := n.copyNode(true)
+	nn.source = n
+	return nn
+}
+
+// copyNode copies this node without its children
+func (n *Node) copyNode(keepIDs bool) *Node {
+	nn := NewNode(n.contents)
+	if keepIDs {
+		nn.id = n.id
+	}
+	nn.padding = n.padding
+	nn.colored = n.colored
+	nn.contentsColored = n.contentsColored
+	for _, attrs := range n.colorsApplied {
+		nn.colorsApplied = append(nn.colorsApplied, append([]color.Attribute(nil), attrs...))
+	}
+	nn.colorRanges = append([]colorRange(nil), n.colorRanges...)
+	nn.branchColor = n.branchColor
+	nn.branchColored = n.branchColored
+	nn.meta = n.meta
+	nn.data = n.data
+	nn.status = n.status
+	nn.diffKind = n.diffKind
+	nn.raw = n.raw
+	nn.rawWidth = n.rawWidth
+	nn.rawContents = n.rawContents
+	for tag := range n.tags {
+		nn.AddTag(tag)
+	}
+	nn.branchDecorator = n.branchDecorator
+	nn.lastDecorator = n.lastDecorator
+	nn.edgeLabel = n.edgeLabel
+	return nn
+}
+
 // String returns a string, satisfying the Stringer interface
 func (n Node) String() string {
 	return n.contents
@@ -210,26 +633,145 @@ func (n Node) String() string {
 // SetContents sets new contents for this node. Please
 // do not use color formatted strings and instead use the provided SetColor* methods.
 func (n *Node) SetContents(newContents string) {
+	if j := n.findJournal(); j != nil {
+		j.record(journalOp{
+			kind:        opSetContents,
+			node:        n,
+			id:          n.id,
+			oldContents: n.contents,
+			newContents: newContents,
+		})
+	}
 	n.contents = newContents
 }
 
-// setPadding sets new padding for this node. Warning:
-// setting padding for individual nodes can cause odd
-// display characteristics.
-func (n *Node) setPadding(padding string) error {
+// TrimContents removes leading and trailing white space,
+// tabs and newlines included, from the contents of this node
+// through SetContents, so it is journaled when it changes
+// anything. Widths are measured at draw time so the next draw
+// lines up.
+func (n *Node) TrimContents() {
+	if trimmed := strings.TrimSpace(n.contents); trimmed != n.contents {
+		n.SetContents(trimmed)
+	}
+}
+
+// TrimContentsAll calls TrimContents on this node and all of
+// its descendents
+func (n *Node) TrimContentsAll() {
+	n.Walk(func(node *Node) bool {
+		node.TrimContents()
+		return true
+	})
+}
+
+// minPaddingWidth is the smallest padding that still leaves
+// room for a branch character, one horizontal line and the
+// space before the contents (e.g., "├─ ")
+const minPaddingWidth = 2
+
+// SetContentsRaw sets contents that are already formatted,
+// e.g. colored by another library, along with the number of
+// columns they take up when displayed, which gree uses for the
+// layout instead of measuring s. The contents reported by
+// String and the exporters are s without its color sequences.
+// The raw text is drawn as is, so gree's own colors are not
+// applied to it and RenderCells shows blanks in its place.
+// If it doesn't fit it is replaced by the plain contents,
+// trimmed. Changing the contents afterwards drops the raw text.
+func (n *Node) SetContentsRaw(s string, visibleWidth int) {
+	plain := ansiEscape.ReplaceAllString(s, "")
+	n.SetContents(plain)
+	n.raw = s
+	n.rawWidth = visibleWidth
+	n.rawContents = plain
+}
+
+// hasRaw reports whether the contents set by SetContentsRaw
+// are still current
+func (n *Node) hasRaw() bool {
+	return n.raw != "" && n.contents == n.rawContents
+}
+
+// SetMeta sets a metadata string (e.g., a file size) for this
+// node which is rendered right-aligned past the tree when
+// DrawInput.MetaColumn is set.
+func (n *Node) SetMeta(s string) *Node {
+	n.meta = s
+	return n
+}
+
+// SetData attaches arbitrary caller data to this node. It
+// is never rendered but is kept by Clone (shallow copied)
+// and serialized by MarshalJSON.
+func (n *Node) SetData(data interface{}) *Node {
+	n.data = data
+	return n
+}
+
+// GetData returns the data attached with SetData
+func (n *Node) GetData() interface{} {
+	if n == nil {
+		return nil
+	}
+	return n.data
+}
+
+// maxMetaWidth returns the length of the longest meta
+// string of the visible nodes in this tree
+func (n *Node) maxMetaWidth(di *DrawInput) (max int) {
+	for _, node := range append([]*Node{n}, n.GetAllDescendents()...) {
+		if !node.visible(di) {
+			continue
+		}
+		if l := VisibleWidth(node.metaText(di)); l > max {
+			max = l
+		}
+	}
+	return max
+}
+
+// SetPadding sets new padding for this node, which sets the
+// length of its connector and how far its children are
+// indented. Padding narrower than minPaddingWidth is extended
+// by repeating its first rune. DrawInput.Padding overrides it.
+func (n *Node) SetPadding(padding string) error {
+	if err := checkPadding(padding); err != nil {
+		return err
+	}
+	n.padding = extendPadding(padding)
+	return nil
+}
+
+// checkPadding returns an error when padding can't be drawn
+func checkPadding(padding string) error {
 	if len(padding) < 1 {
 		return errors.New("padding must be greater than len(1)")
 	}
-	n.padding = padding
+	if strings.ContainsRune(padding, '\t') {
+		return errors.New("padding must not contain tabs since their display width is unknown, use spaces or DrawInput.TabWidth")
+	}
 	return nil
 }
 
-// SetPadding sets new padding for this node
+// extendPadding repeats the first rune of padding until
+// it is at least minPaddingWidth runes long
+func extendPadding(padding string) string {
+	if short := minPaddingWidth - utf8.RuneCountInString(padding); short > 0 {
+		padding += strings.Repeat(firstRuneChar(padding), short)
+	}
+	return padding
+}
+
+// SetPaddingAll sets new padding for this node
 // and all of it's descendents.
 func (n *Node) SetPaddingAll(padding string) (err error) {
-	n.padding = padding
+	err = n.SetPadding(padding)
+	if err != nil {
+		return err
+	}
 	for _, node := range n.GetAllDescendents() {
-		err = node.setPadding(padding)
+		err = node.SetPadding(padding)
 		if err != nil {
 			return err
 		}
@@ -238,23 +780,21 @@ func (n *Node) SetPaddingAll(padding string) (err error) {
 }
 
 // GetAllDescendents gets all descendents of this node
-// and returns a slice of pointers. Useful
+// and returns a slice of pointers in the same order as Walk,
+// each node before its children. The order only depends on
+// the tree, not on previous draws or SetDrawOrder. Useful
 // for updating them.
 func (n *Node) GetAllDescendents() (all []*Node) {
-	all = append(all, n.children...)
+	if n == nil {
+		return nil
+	}
 	for _, child := range n.children {
+		all = append(all, child)
 		all = append(all, child.GetAllDescendents()...)
 	}
-	sort.Slice(all, func(i, j int) bool {
-		return all[i].index < all[j].index
-	})
 	return all
 }
 
-const (
-	blankUUID string = "00000000-0000-0000-0000-000000000000"
-)
-
 // NewChild adds a child with contents of the passed
 // string to this Node's children. It returns the pointer
 // to the new Node. This can be discarded or used for chaining
@@ -262,24 +802,215 @@ const (
 //
 // Please do not use color formatted strings and instead use the provided SetColor* methods.
 func (n *Node) NewChild(contents string) *Node {
-	if n.id.String() == blankUUID {
-		n.id = uuid.New()
+	if n == nil {
+		return nil
 	}
 	nn := n.AddChild(NewNode(contents))
 	return nn
 }
 
+// Add adds each of children to this Node and returns this
+// Node for chaining. A string is added as a new child with
+// that contents (see NewChild) and a *Node is attached as is
+// (see AddChild). Any other type, including a nil *Node, is a
+// programming error and panics before anything is added.
+func (n *Node) Add(children ...interface{}) *Node {
+	for _, child := range children {
+		switch c := child.(type) {
+		case string:
+		case *Node:
+			if c == nil {
+				panic("gree: Add called with a nil *Node")
+			}
+		default:
+			panic(fmt.Sprintf("gree: Add called with unsupported type %T", child))
+		}
+	}
+	for _, child := range children {
+		switch c := child.(type) {
+		case string:
+			n.NewChild(c)
+		case *Node:
+			n.AddChild(c)
+		}
+	}
+	return n
+}
+
+// ErrCycle is returned when a node would end up beneath
+// itself, which would make a cycle nothing that walks the
+// tree could get out of
+var ErrCycle = errors.New("cannot add a node beneath itself")
+
 // AddChild adds the given Node to the children
-// of the current Node
+// of the current Node. If the given Node already has
+// another parent it is detached from that parent first,
+// the same as calling MoveTo. Adding a Node that is
+// already a child changes nothing, it keeps its position
+// among its siblings. Nothing is added and nil is
+// returned when the given Node is the current Node or
+// one of its ancestors, see TryAddChild to get the reason.
 func (n *Node) AddChild(nc *Node) *Node {
-	if n.id.String() == blankUUID {
-		n.id = uuid.New()
+	if err := n.TryAddChild(nc); err != nil {
+		return nil
+	}
+	return nc
+}
+
+// TryAddChild is like AddChild but returns an error instead
+// of nil when nothing was added: ErrCycle when nc is this
+// Node or one of its ancestors.
+func (n *Node) TryAddChild(nc *Node) error {
+	if n == nil || nc == nil {
+		return errors.New("nodes must not be nil")
+	}
+	n.ensureInit()
+	nc.ensureInit()
+	if nc.Contains(n) {
+		return ErrCycle
+	}
+	if nc.parent == n {
+		return nil
+	}
+	if nc.parent != nil {
+		return nc.MoveTo(n)
+	}
+	if j := n.findJournal(); j != nil {
+		j.record(journalOp{
+			kind:      opAdd,
+			node:      nc,
+			id:        nc.id,
+			newParent: n,
+			newIndex:  len(n.children),
+		})
+	}
+	n.insertChild(len(n.children), nc)
+	return nil
+}
+
+// RemoveChild detaches the y'th child from this Node
+// and returns it. The returned Node becomes the root of
+// its own tree. If the y'th child does not exist a nil
+// pointer is returned.
+func (n *Node) RemoveChild(y int) *Node {
+	if y < 0 || y >= len(n.children) {
+		return nil
+	}
+	if j := n.findJournal(); j != nil {
+		j.record(journalOp{
+			kind:      opRemove,
+			node:      n.children[y],
+			id:        n.children[y].id,
+			oldParent: n,
+			oldIndex:  y,
+		})
+	}
+	return n.removeChildAt(y)
+}
+
+// MoveTo detaches this Node from its current parent and
+// adds it as the last child of newParent. ErrCycle is
+// returned if newParent is this Node or one of its
+// descendents.
+func (n *Node) MoveTo(newParent *Node) error {
+	if newParent == nil {
+		return errors.New("new parent must not be nil")
+	}
+	if n.Contains(newParent) {
+		return ErrCycle
+	}
+	n.moveTo(newParent, -1)
+	return nil
+}
+
+// moveTo is MoveTo without the checks, placing this Node at
+// position i of newParent's children once it has been detached,
+// or last when i is out of range
+func (n *Node) moveTo(newParent *Node, i int) {
+	j := n.findJournal()
+	if j == nil {
+		j = newParent.findJournal()
+	}
+	op := journalOp{
+		kind:      opMove,
+		node:      n,
+		id:        n.id,
+		oldParent: n.parent,
+		oldIndex:  -1,
+		newParent: newParent,
+	}
+	if n.parent != nil {
+		op.oldIndex = n.parent.childIndex(n)
+		n.parent.removeChildAt(op.oldIndex)
+	}
+	op.newIndex = i
+	if i < 0 || i > len(newParent.children) {
+		op.newIndex = len(newParent.children)
+	}
+	if j != nil {
+		j.record(op)
+	}
+	newParent.insertChild(op.newIndex, n)
+}
+
+// ReparentGeneration moves every node of the y'th generation
+// of this node (see GetGeneration) beneath newParent, keeping
+// their order and their own children. Nothing is moved and an
+// error is returned if newParent is one of the moved nodes or
+// beneath one of them.
+func (n *Node) ReparentGeneration(y int, newParent *Node) error {
+	if newParent == nil {
+		return errors.New("new parent must not be nil")
+	}
+	gen := n.GetGeneration(y)
+	for _, node := range gen {
+		if node.Contains(newParent) {
+			return ErrCycle
+		}
+	}
+	for _, node := range gen {
+		if err := node.MoveTo(newParent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// childIndex returns the position of c within this Node's
+// children or -1 if c is not a child
+func (n *Node) childIndex(c *Node) int {
+	for i, child := range n.children {
+		if child == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// insertChild places nc at position i of this Node's children
+// and updates depths. It does not touch the journal.
+func (n *Node) insertChild(i int, nc *Node) {
+	if i < 0 || i > len(n.children) {
+		i = len(n.children)
 	}
 	nc.parent = n
 	nc.depth = n.depth + 1
-	n.children = append(n.children, nc)
+	n.children = append(n.children, nil)
+	copy(n.children[i+1:], n.children[i:])
+	n.children[i] = nc
+	n.drawOrder = nil
 	n.updateDepths()
-	return nc
+}
+
+// removeChildAt detaches the i'th child and updates depths.
+// It does not touch the journal.
+func (n *Node) removeChildAt(i int) *Node {
+	c := n.children[i]
+	n.children = append(n.children[:i], n.children[i+1:]...)
+	n.drawOrder = nil
+	c.parent = nil
+	c.updateDepths()
+	return c
 }
 
 func (n *Node) updateDepths() {
@@ -297,18 +1028,208 @@ func (n *Node) updateDepths() {
 
 // DrawInput holds input options for the DrawOptions method
 type DrawInput struct {
-	Border  bool   // whether or not to draw a border
-	Debug   bool   // whether or not to add debug info to output
-	Padding string // rendered padding for this and child nodes
+	Border bool // whether or not to draw a border
+	Debug  bool // whether or not to add debug info to output
+	// RulerInterval is the number of columns between the ticks
+	// of the Debug ruler, defaults to 5
+	RulerInterval int
+	// Padding is rendered for this and child nodes in place of
+	// their own padding, which is left as is. Its runes are repeated between the
+	// connectors of each level so it can double as a guide, e.g.
+	// "·· " draws dotted indentation.
+	Padding string
+	// MinDepth hides nodes above this depth (relative to the
+	// drawn node) and renders the nodes at this depth as roots
+	// at the left margin. Defaults to 0 which shows the root.
+	MinDepth int
+	// MaxDepth hides nodes below this depth (relative to the
+	// drawn node). Defaults to 0 which means no limit.
+	MaxDepth int
+	// TabWidth is the number of columns a tab in Padding is
+	// expanded to before layout. Defaults to 4.
+	TabWidth int
+	// ShowParentStub draws a continuation line above the root
+	// when the drawn node has a parent, to show that it is a
+	// subtree of a larger tree
+	ShowParentStub bool
+	// ArrowStyle ends each connector with an arrowhead pointing
+	// at the child (e.g., "├─▶ child") to emphasize direction
+	ArrowStyle bool
+	// Legend maps labels to colors and is rendered below the
+	// tree as one colored swatch and label per line, sorted
+	// by label. It does not affect the width of the tree.
+	Legend map[string]color.Attribute
+	// MetaColumn renders each node's SetMeta string right-aligned
+	// in a column past the widest row of the tree
+	MetaColumn bool
+	// Stacked puts each node's connector on its own row with the
+	// label on the row below it, so children descend from their
+	// parent's label instead of sharing a row with the connector
+	Stacked bool
+	// MinLabelColumn moves the whole tree right, when needed, so
+	// that no label starts before this column (counted from zero,
+	// border included). It lines up trees drawn separately. The
+	// gap is filled like the padding. Defaults to 0, no minimum.
+	MinLabelColumn int
+	// ParentBranchMark ends the connector of each node with
+	// drawn children with a '┬' the children hang from, e.g.
+	// "├─┬ child1" with "│ └── grandchild1" below it. The
+	// stacked layout and custom decorators are left as is.
+	ParentBranchMark bool
+	// ShowEdgeLabels draws each node's SetEdgeLabel string on
+	// the connector from its parent, e.g. "├──[0.7]── child"
+	ShowEdgeLabels bool
+	// ShowIndex prefixes the label of each node that has a
+	// parent with its position among its siblings, the index
+	// to pass to GetChild (e.g., "[1] child2")
+	ShowIndex bool
+	// ShowStatus prefixes each node that has a status set with
+	// the glyph for that status (see SetStatus)
+	ShowStatus bool
+	// ColorStatus colors the label of each node that has a
+	// status but no colors of its own with its status colors.
+	// Only used when ShowStatus is set.
+	ColorStatus bool
+	// StatusTheme overrides the glyph and colors of individual
+	// statuses, see DefaultStatusTheme for the defaults
+	StatusTheme map[Status]StatusStyle
+	// MaxWidth is the most columns the drawing may use. When the
+	// tree is wider, the children of the nodes whose subtrees
+	// overflow are moved to separate blocks drawn below the tree
+	// and referenced by a footnote marker (e.g., "child1 [1]").
+	// Labels that still don't fit are trimmed. With Border the
+	// drawing is at least 2 columns wide. Defaults to 0 which
+	// means no limit.
+	MaxWidth int
+	// SummarizeDeeperThan draws the nodes down to this depth
+	// (relative to the drawn node) and replaces the children of
+	// the nodes at this depth with a single summary row, e.g.
+	// "... (12 nodes, 4 leaves)". Defaults to 0 which draws all.
+	SummarizeDeeperThan int
+	// FixedWidth makes every row of the drawing, border included,
+	// exactly this many columns wide. Shorter rows are padded
+	// with spaces and labels that don't fit are trimmed. It
+	// takes precedence over MaxWidth and the terminal width.
+	// With Border the drawing is at least 2 columns wide.
+	// Defaults to 0 which sizes the drawing to the tree.
+	FixedWidth int
+	// PadSiblingsToMax pads each label to the width of the
+	// widest label among its siblings so each group of children
+	// lines up as a column. The padding is left uncolored.
+	PadSiblingsToMax bool
+	// PadFill is the rune PadSiblingsToMax pads with, defaults
+	// to a space
+	PadFill rune
+	// EmptyPlaceholder is drawn in place of the label of nodes
+	// with empty contents, e.g. "(empty)". Defaults to "" which
+	// draws the decorator alone.
+	EmptyPlaceholder string
+	// PrefixFunc and SuffixFunc, when set, are called for every
+	// node drawn and what they return is drawn right before and
+	// after its contents and colored with them, e.g. to prefix
+	// leaves with "📄". They're measured like the contents.
+	PrefixFunc func(*Node) string
+	SuffixFunc func(*Node) string
+	// Reducer is called for every node, children first, with
+	// the results of its children and its own result is drawn
+	// after its label as " = result", to show a bottom up
+	// aggregation such as the sum of a subtree. Children that
+	// aren't drawn, e.g. below MaxDepth or moved to another
+	// block by MaxWidth, are still reduced. Rows that don't
+	// stand for a node, like the SummarizeDeeperThan summary,
+	// get no result.
+	Reducer func(node *Node, childResults []string) string
+	// RedactLabels masks every column of the contents, edge
+	// labels and meta of each node with RedactMask while keeping
+	// the layout, to share the shape of a tree without its names
+	RedactLabels bool
+	// RedactMask is the rune RedactLabels masks with, defaults
+	// to '•'
+	RedactMask rune
+	// PruneEmpty leaves out nodes with empty contents whose
+	// descendents are all empty as well. The node being drawn
+	// is never left out.
+	PruneEmpty bool
+	// Isolated draws a copy of the tree so the nodes being
+	// drawn are only read, never written, which makes it safe
+	// to draw the same tree from several goroutines at once
+	// as long as nothing modifies it meanwhile. Copying costs
+	// an allocation per node, see BenchmarkDrawIsolated.
+	Isolated bool
+	// ChainInline draws each run of nodes that have a single
+	// child on one row, joined by ChainSeparator, instead of
+	// indenting every level (see FlattenChain)
+	ChainInline bool
+	// ChainSeparator joins the contents of a ChainInline run,
+	// defaults to "/"
+	ChainSeparator string
+	// Inverted draws the tree upside down with the root at the
+	// bottom and the connectors mirrored to branch upwards
+	Inverted bool
+	// Zebra draws every other row of the tree on a ZebraColor
+	// background, across the full width of the row
+	Zebra bool
+	// ZebraColor is the background used by Zebra, defaults to
+	// color.BgHiBlack
+	ZebraColor color.Attribute
+	// TagColors colors the label of each node that has no
+	// colors of its own with the colors of its tags, taking
+	// precedence over ColorStatus
+	TagColors map[string]color.Attribute
+	// DimNonMatching draws the label of each node whose contents
+	// don't contain this string faint, on top of its other
+	// colors, so matches stand out while keeping their context
+	DimNonMatching string
+	// ViewportHeight limits the drawing to this many rows of the
+	// tree starting at row ViewportTop, for scrolling. Rows
+	// outside of the window aren't rendered but the width is
+	// still that of the whole tree so it doesn't change while
+	// scrolling. Borders are drawn around the window. Defaults
+	// to 0 which draws every row.
+	ViewportHeight int
+	// ViewportTop is the first row of the tree drawn when
+	// ViewportHeight is set
+	ViewportTop int
+	// RootLabel replaces the contents of the node being drawn
+	// in its row (e.g., "." for a directory tree) without
+	// changing the node itself
+	RootLabel string
+	// Ellipsis ends labels that are trimmed because they don't
+	// fit in MaxWidth or the terminal, defaults to "..."
+	Ellipsis string
+
+	footnoted bool             // set while drawing the blocks of a MaxWidth split
+	reduced   map[*Node]string // Reducer results of the original nodes, see withReduced
+	metaWidth int              // columns MetaColumn takes up in the current draw, gap excluded
+}
+
+// expandTabs replaces each tab in s with enough spaces
+// to reach the next multiple of tabWidth
+func expandTabs(s string, tabWidth int) string {
+	if tabWidth < 1 {
+		tabWidth = 4
+	}
+	var b strings.Builder
+	col := 0
+	for _, r := range s {
+		if r == '\t' {
+			spaces := tabWidth - col%tabWidth
+			b.WriteString(strings.Repeat(" ", spaces))
+			col += spaces
+			continue
+		}
+		b.WriteRune(r)
+		col++
+	}
+	return b.String()
 }
 
 // Draw sets default input options and returns a string
 // of the rendered tree for this Node as if this node is root
 func (n *Node) Draw() (rendering string) {
 	di := DrawInput{
-		Border:  false,
-		Debug:   false,
-		Padding: n.padding,
+		Border: false,
+		Debug:  false,
 	}
 	rendering = n.DrawOptions(&di)
 	return rendering
@@ -316,9 +1237,22 @@ func (n *Node) Draw() (rendering string) {
 
 type rrow struct {
 	contents map[int]rune
+	colors   map[int][]color.Attribute
+	raw      map[int]string // preformatted text written as is
 	width    int
 }
 
+// setRaw draws s as is at column i, taking up width columns
+func (r *rrow) setRaw(i int, s string, width int) {
+	if i < 0 || i > r.width {
+		return
+	}
+	r.raw[i] = s
+	for j := i + 1; j < i+width && j <= r.width; j++ {
+		r.contents[j] = wideTail
+	}
+}
+
 func (r *rrow) setRowI(i int, ru rune, override bool) {
 	if r.width >= i {
 		if override && r.contents[i] != 0 {
@@ -330,13 +1264,17 @@ func (r *rrow) setRowI(i int, ru rune, override bool) {
 }
 
 func (r *rrow) appendString(afterI int, s string) {
-	istr := []rune(s)
-	for i := 0; i <= r.width; i++ {
-		if i == afterI {
-			for j := 0; j < len(istr); j++ {
-				r.setRowI(i+j, istr[j], false)
-			}
-			break
+	if afterI < 0 || afterI > r.width {
+		return
+	}
+	i := afterI
+	for _, ru := range s {
+		r.setRowI(i, ru, false)
+		i++
+		for w := runeWidth(ru); w > 1; w-- {
+			// the rune spills into the next column
+			r.setRowI(i, wideTail, false)
+			i++
 		}
 	}
 }
@@ -345,92 +1283,552 @@ func (r rrow) toRunes() []rune {
 	return []rune(r.str())
 }
 
-func (r rrow) str() string {
-	var results []rune
-	for i := 0; i <= r.width; i++ {
-		results = append(results, r.contents[i])
+// setColorI colors the rune at position i when the row is
+// converted to a string. Colors are applied on output so
+// they never take up any of the row's cells.
+func (r *rrow) setColorI(i int, attrs ...color.Attribute) {
+	if r.width >= i {
+		r.colors[i] = attrs
 	}
-	return string(results)
 }
 
-func newRrow(width int) *rrow {
-	nrr := rrow{
-		contents: make(map[int]rune, width),
-		width:    width,
+// flipVertical mirrors the connectors in the cells up to and
+// including end so they point up instead of down
+func (r *rrow) flipVertical(end int) {
+	for i := 0; i <= end && i <= r.width; i++ {
+		switch r.contents[i] {
+		case '└':
+			r.contents[i] = '┌'
+		case '┐':
+			r.contents[i] = '┘'
+		case '┬':
+			r.contents[i] = '┴'
+		}
 	}
-	return &nrr
 }
 
-func vbar() rune {
-	return []rune("│")[0]
+// setBackground puts attr underneath the colors of every
+// cell of the row, so the cells' own colors win
+func (r *rrow) setBackground(attr color.Attribute) {
+	for i := 0; i <= r.width; i++ {
+		r.colors[i] = append([]color.Attribute{attr}, r.colors[i]...)
+	}
 }
 
-func (n *Node) trimToSize(maxwidth int) string {
-	var newRunes []rune
-	nlen := utf8.RuneCountInString(n.contents) // grab non-colored contents
-	if n.x1+nlen > maxwidth {
-		maxConLen := maxwidth - n.x1 - 20
-		for i, r := range n.contents {
-			if i > maxConLen {
-				break
-			}
-			newRunes = append(newRunes, r)
+func sameAttrs(a, b []color.Attribute) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
 		}
-		newString := string(newRunes) + "..."
-		return newString
 	}
-	// if content length is under width then we return as is
-	return n.contents
+	return true
 }
 
-func (n *Node) render(width int, border bool) (row *rrow) {
-	var repr string
-	n.contentsTrimmed = n.trimToSize(width)
-	n.contentsColored = n.reColor()
-	if n.colored {
-		width = width + (utf8.RuneCountInString(n.contentsColored) - utf8.RuneCountInString(n.contentsTrimmed))
-		repr = n.contentsColored
-	} else {
-		repr = n.contentsTrimmed
-	}
-	row = newRrow(width)
-	for x := 0; x <= width; x++ {
-		if (x == 0 || x == width) && border {
-			row.setRowI(x, vbar(), true)
+func (r rrow) str() string {
+	var results strings.Builder
+	for i := 0; i <= r.width; i++ {
+		if s, ok := r.raw[i]; ok {
+			results.WriteString(s)
+			continue
 		}
-		for _, p := range n.lineage {
-			if x == p.x1 {
-				if !p.amLastSibling && !p.isRoot {
-					row.setRowI(x, vbar(), false)
-				}
+		attrs, ok := r.colors[i]
+		if !ok {
+			if r.contents[i] != wideTail {
+				results.WriteRune(r.contents[i])
 			}
+			continue
 		}
-		if x == n.x1 {
-			row.appendString(x, n.genDecorator(0)+repr)
-		} else {
-			row.setRowI(x, n.padRune(), false)
+		// group consecutive runes of the same color
+		var run []rune
+		for ; i <= r.width; i++ {
+			if _, isRaw := r.raw[i]; isRaw {
+				i--
+				break
+			}
+			if a, ok := r.colors[i]; !ok || !sameAttrs(a, attrs) {
+				i--
+				break
+			}
+			if r.contents[i] != wideTail {
+				run = append(run, r.contents[i])
+			}
 		}
+		results.WriteString(color.New(attrs...).Sprint(string(run)))
 	}
-	return row
+	return results.String()
 }
 
-func (n *Node) genDecorator(decLength int) string {
+func newRrow(width int) *rrow {
+	nrr := rrow{
+		contents: make(map[int]rune, width),
+		colors:   make(map[int][]color.Attribute),
+		raw:      make(map[int]string),
+		width:    width,
+	}
+	return &nrr
+}
+
+func vbar() rune {
+	return theme.Vertical
+}
+
+// trimToSize shortens the label to fit between its column
+// and maxwidth, ending it with di.Ellipsis
+func (n *Node) trimToSize(maxwidth int, di *DrawInput) string {
+	available := maxwidth - n.labelColumn() + 1
+	if di.Border {
+		available--
+	}
+	if di.metaWidth > 0 {
+		// keep a space between the label and the meta column
+		available -= di.metaWidth + 1
+	}
+	if VisibleWidth(n.label) <= available {
+		// if label length is under width then we return as is
+		return n.label
+	}
+	ellipsis := di.Ellipsis
+	if ellipsis == "" {
+		ellipsis = "..."
+	}
+	if available <= VisibleWidth(ellipsis) {
+		return truncateWidth(ellipsis, available)
+	}
+	return truncateWidth(n.label, available-VisibleWidth(ellipsis)) + ellipsis
+}
+
+// genLabel returns the uncolored text to render for this
+// node, which is its contents plus any decoration requested
+// by the DrawInput. top is set for the node being drawn.
+func (n *Node) genLabel(di *DrawInput, top bool) string {
+	return n.labelText(di, top, n.drawsRaw(di, top))
+}
+
+// drawsRaw reports whether the raw contents are drawn
+func (n *Node) drawsRaw(di *DrawInput, top bool) bool {
+	return n.hasRaw() && !(top && di.RootLabel != "") && !di.RedactLabels
+}
+
+// suffixText returns what di.SuffixFunc and di.Reducer add
+// after the contents of this node
+func (n *Node) suffixText(di *DrawInput) (suffix string) {
+	if di.SuffixFunc != nil {
+		suffix = di.SuffixFunc(n)
+	}
+	if result, ok := di.reduced[n.original()]; ok {
+		suffix += " = " + result
+	}
+	return suffix
+}
+
+// withReduced returns di with the Reducer results of this
+// tree, or di itself when they are already there or there's
+// no Reducer. It is called on the tree a draw starts from so
+// the copies drawn in its place (e.g., the blocks of a
+// MaxWidth split) show the results of the whole tree.
+func (n *Node) withReduced(di *DrawInput) *DrawInput {
+	if di.Reducer == nil || di.reduced != nil {
+		return di
+	}
+	sub := *di
+	sub.reduced = make(map[*Node]string)
+	n.reduce(di.Reducer, sub.reduced)
+	return &sub
+}
+
+// reduce runs reducer over this node and its descendents,
+// children first, keeping each result in results
+func (n *Node) reduce(reducer func(node *Node, childResults []string) string, results map[*Node]string) string {
+	childResults := make([]string, 0, len(n.children))
+	for _, child := range n.children {
+		childResults = append(childResults, child.reduce(reducer, results))
+	}
+	results[n] = reducer(n, childResults)
+	return results[n]
+}
+
+// redact masks each column of s with di.RedactMask
+func (di *DrawInput) redact(s string) string {
+	mask := di.RedactMask
+	if mask == 0 {
+		mask = '•'
+	}
+	var b strings.Builder
+	for _, r := range s {
+		b.WriteString(strings.Repeat(string(mask), runeWidth(r)))
+	}
+	return b.String()
+}
+
+// metaText returns this node's meta as drawn by MetaColumn
+func (n *Node) metaText(di *DrawInput) string {
+	if di.RedactLabels {
+		return di.redact(n.meta)
+	}
+	return n.meta
+}
+
+// labelText builds the label, leaving blank room for the raw
+// contents when raw is set
+func (n *Node) labelText(di *DrawInput, top, raw bool) string {
+	label := n.contents
+	if di.RedactLabels {
+		label = di.redact(label)
+	}
+	if top && di.RootLabel != "" {
+		label = di.RootLabel
+	}
+	if raw {
+		label = strings.Repeat(" ", n.rawWidth)
+	}
+	if label == "" {
+		label = di.EmptyPlaceholder
+	}
+	if n.summarized {
+		return label
+	}
+	if di.PrefixFunc != nil {
+		label = di.PrefixFunc(n) + label
+	}
+	label += n.suffixText(di)
+	if di.ShowStatus && n.status != NoStatus {
+		label = di.statusStyle(n.status).Glyph + " " + label
+	}
+	if orig := n.original(); di.ShowIndex && orig.parent != nil && !n.footnoteRoot {
+		label = fmt.Sprintf("[%d] %s", orig.parent.childIndex(orig), label)
+	}
+	return label
+}
+
+// colorGroups returns the color attributes to apply to the
+// label for this draw
+func (n *Node) colorGroups(di *DrawInput) [][]color.Attribute {
+	groups := n.labelColors(di)
+	if di.DimNonMatching != "" && !strings.Contains(n.contents, di.DimNonMatching) {
+		// full slice expression so the node's colors aren't touched
+		groups = append(groups[:len(groups):len(groups)], []color.Attribute{color.Faint})
+	}
+	return groups
+}
+
+// labelColors returns the colors of this node's label before
+// DimNonMatching is applied
+func (n *Node) labelColors(di *DrawInput) [][]color.Attribute {
+	if len(n.colorsApplied) == 0 && len(di.TagColors) > 0 {
+		if attrs := n.tagColors(di); len(attrs) > 0 {
+			return [][]color.Attribute{attrs}
+		}
+	}
+	if len(n.colorsApplied) == 0 && di.ShowStatus && di.ColorStatus && n.status != NoStatus {
+		if attrs := di.statusStyle(n.status).Colors; len(attrs) > 0 {
+			return [][]color.Attribute{attrs}
+		}
+	}
+	return n.colorsApplied
+}
+
+func (n *Node) render(width int, di *DrawInput) (row *rrow) {
+	border := di.Border
+	var repr string
+	groups := n.colorGroups(di)
+	n.contentsTrimmed = n.trimToSize(width, di)
+	raw := n.rawLabel && n.contentsTrimmed == n.label
+	if n.rawLabel && !raw {
+		// the raw contents don't fit, trim the plain ones instead
+		n.label = n.labelText(di, n.rdepth == 0, false) + strings.Repeat(" ", n.labelPad)
+		n.contentsTrimmed = n.trimToSize(width, di)
+	}
+	n.contentsColored = n.reColor(groups)
+	repr = n.contentsTrimmed
+	row = newRrow(width)
+	guides := n.guides(di.Stacked && !n.isRoot)
+	defer func() {
+		if raw {
+			start := n.labelColumn() + VisibleWidth(n.label) - n.labelPad - VisibleWidth(n.suffixText(di)) - n.rawWidth
+			row.setRaw(start, n.raw, n.rawWidth)
+			return
+		}
+		// color the label cells once the row is laid out so
+		// the escape sequences don't count towards the width
+		colorLen := VisibleWidth(repr)
+		if repr == n.label {
+			colorLen -= n.labelPad
+		}
+		if len(groups) > 0 {
+			var attrs []color.Attribute
+			for _, group := range groups {
+				attrs = append(attrs, group...)
+			}
+			for i := 0; i < colorLen; i++ {
+				row.setColorI(n.labelColumn()+i, attrs...)
+			}
+		}
+		n.applyColorRanges(row, n.labelColumn()+colorLen, di)
+	}()
+	for x := 0; x <= width; x++ {
+		if (x == 0 || x == width) && border {
+			row.setRowI(x, vbar(), true)
+		}
+		for i, p := range n.lineage {
+			if x == p.x1 {
+				if !p.amLastSibling && !p.isRoot && !p.hidden {
+					row.setRowI(x, vbar(), false)
+					if attr, ok := effectiveBranchColor(n.lineage[:i+1]); ok {
+						row.setColorI(x, attr)
+					}
+				}
+			}
+		}
+		if di.Stacked && !n.isRoot {
+			// the connector is on the row above, just continue
+			// the vertical bar down to the next sibling
+			if x == n.x1 && !n.amLastSibling {
+				row.setRowI(x, vbar(), false)
+				if attr, ok := effectiveBranchColor(append(n.lineage, n)); ok {
+					row.setColorI(x, attr)
+				}
+			}
+			if x == n.labelColumn() {
+				row.appendString(x, repr)
+			} else {
+				row.setRowI(x, n.fillRune(guides, x), false)
+			}
+			continue
+		}
+		if x == n.x1 {
+			decorator := n.decorator
+			row.appendString(x, decorator+repr)
+			if attr, ok := effectiveBranchColor(append(n.lineage, n)); ok {
+				// leave the trailing space uncolored
+				for i := 0; i < VisibleWidth(decorator)-1; i++ {
+					row.setColorI(x+i, attr)
+				}
+			}
+		} else {
+			row.setRowI(x, n.fillRune(guides, x), false)
+		}
+	}
+	if di.MetaColumn && n.meta != "" {
+		end := width
+		if border {
+			end--
+		}
+		meta := n.metaText(di)
+		col := end - VisibleWidth(meta) + 1
+		row.setRowI(col-1, ' ', true)
+		for _, r := range meta {
+			row.setRowI(col, r, true)
+			col++
+			for w := runeWidth(r); w > 1; w-- {
+				row.setRowI(col, wideTail, true)
+				col++
+			}
+		}
+	}
+	return row
+}
+
+// renderConnector renders the row above this node's label
+// in the stacked layout, which branches off the parent's
+// vertical bar and turns down into the label's column
+func (n *Node) renderConnector(width int, di *DrawInput) (row *rrow) {
+	row = newRrow(width)
+	connector := []rune(sibCharS())
+	if n.amLastSibling {
+		connector = []rune(sibCharLastS())
+	}
+	if edge := n.edgeText(di); edge != "" {
+		line := strings.Repeat(horos(), utf8.RuneCountInString(n.drawPadding)-1)
+		connector = append(connector, []rune(line+edge+line+horos())...)
+	} else {
+		connector = append(connector, []rune(strings.Repeat(horos(), VisibleWidth(n.decorator)-1))...)
+	}
+	connector = append(connector, []rune(stackChar())...)
+	guides := n.guides(false)
+	for x := 0; x <= width; x++ {
+		if (x == 0 || x == width) && di.Border {
+			row.setRowI(x, vbar(), true)
+		}
+		for i, p := range n.lineage {
+			if x == p.x1 && !p.amLastSibling && !p.isRoot && !p.hidden {
+				row.setRowI(x, vbar(), false)
+				if attr, ok := effectiveBranchColor(n.lineage[:i+1]); ok {
+					row.setColorI(x, attr)
+				}
+			}
+		}
+		if x >= n.x1 && x < n.x1+len(connector) {
+			row.setRowI(x, connector[x-n.x1], false)
+			if attr, ok := effectiveBranchColor(append(n.lineage, n)); ok {
+				row.setColorI(x, attr)
+			}
+		}
+		row.setRowI(x, n.fillRune(guides, x), false)
+	}
+	return row
+}
+
+// renderStub renders a row with a continuation line
+// in this node's column
+func (n *Node) renderStub(width int, border bool) (row *rrow) {
+	row = newRrow(width)
+	for x := 0; x <= width; x++ {
+		if (x == 0 || x == width) && border {
+			row.setRowI(x, vbar(), true)
+		}
+		if x == n.x1 {
+			row.setRowI(x, stubChar(), false)
+		}
+		row.setRowI(x, n.padRune(), false)
+	}
+	return row
+}
+
+// SetDecorator overrides the connector drawn before this
+// node's label, e.g. "╫──" to make symlinks stand out. The
+// branch string is used when the node has siblings after it
+// and lastBranch when it is the last child. A space is added
+// before the label like with the default connectors. Passing
+// an empty string uses the default connector for that position
+// so passing two restores the default. Decorators are measured
+// by their display width so wide runes such as emoji keep the
+// children lined up. The stacked layout ignores custom
+// decorators but keeps their width.
+func (n *Node) SetDecorator(branch, lastBranch string) *Node {
+	n.branchDecorator = branch
+	n.lastDecorator = lastBranch
+	return n
+}
+
+// SetEdgeLabel sets the label of the edge connecting this
+// node to its parent, such as a weight or a probability. It
+// is drawn on the connector when DrawInput.ShowEdgeLabels is
+// set, which grows to fit it. Custom decorators ignore it.
+func (n *Node) SetEdgeLabel(s string) *Node {
+	n.edgeLabel = s
+	return n
+}
+
+// GetEdgeLabel returns the label set by SetEdgeLabel
+func (n *Node) GetEdgeLabel() string {
+	if n == nil {
+		return ""
+	}
+	return n.edgeLabel
+}
+
+// branchesHere reports whether this node's connector gets the
+// DrawInput.ParentBranchMark its drawn children hang from,
+// must be called from relate once the depth is set
+func (n *Node) branchesHere(di *DrawInput) bool {
+	if !di.ParentBranchMark || di.Stacked || n.isRoot || n.hidden {
+		return false
+	}
+	if n.branchDecorator != "" || n.lastDecorator != "" {
+		return false
+	}
+	if di.MaxDepth > 0 && n.rdepth >= di.MaxDepth {
+		return false
+	}
+	for _, child := range n.children {
+		if !di.PruneEmpty || !child.isEmpty() {
+			return true
+		}
+	}
+	return false
+}
+
+// edgeText returns the edge label as drawn on the connector,
+// or an empty string when there's nothing to draw
+func (n *Node) edgeText(di *DrawInput) string {
+	if !di.ShowEdgeLabels || n.edgeLabel == "" {
+		return ""
+	}
+	if di.RedactLabels {
+		return "[" + di.redact(n.edgeLabel) + "]"
+	}
+	return "[" + n.edgeLabel + "]"
+}
+
+func (n *Node) genDecorator(decLength int, di *DrawInput) string {
 	if n.isRoot {
 		return ""
 	}
-	length := utf8.RuneCountInString(n.padding) - 1
+	// a custom decorator left empty for this position falls back
+	// to the default so children don't hang right next to it
+	if n.amLastSibling && n.lastDecorator != "" {
+		return n.lastDecorator + " "
+	}
+	if !n.amLastSibling && n.branchDecorator != "" {
+		return n.branchDecorator + " "
+	}
+	length := utf8.RuneCountInString(n.drawPadding) - 1
 	if decLength != 0 {
 		length = decLength
 	}
+	line := strings.Repeat(horos(), length)
+	if edge := n.edgeText(di); edge != "" {
+		line += edge + line
+	}
+	if n.marked {
+		line = strings.TrimSuffix(line, horos()) + string(theme.BranchMark)
+	} else if di.ArrowStyle {
+		// swap the last horizontal for an arrowhead
+		line = strings.TrimSuffix(line, horos()) + arrowHead()
+	}
 	if n.amLastSibling && !n.isRoot {
-		return sibCharLastS() + strings.Repeat(horos(), length) + " "
+		return sibCharLastS() + line + " "
 	} else {
-		return sibCharS() + strings.Repeat(horos(), length) + " "
+		return sibCharS() + line + " "
 	}
 }
 
 func (n Node) padRune() rune {
-	return []rune(firstRuneChar(n.padding))[0]
+	return []rune(firstRuneChar(n.drawPadding))[0]
+}
+
+// guides returns the indentation runes for the columns between
+// the connectors of this node's ancestors, taken from each
+// ancestor's padding so a padding such as "·· " draws dotted
+// guides. Vertical bars are drawn over the column of each
+// ancestor's connector. With self the columns before this
+// node's own label are included as well.
+func (n *Node) guides(self bool) map[int]rune {
+	guides := make(map[int]rune)
+	levels := n.lineage
+	if self {
+		levels = append(levels[:len(levels):len(levels)], n)
+	}
+	for _, p := range levels {
+		if p.isRoot || p.hidden {
+			continue
+		}
+		pattern := []rune(p.drawPadding)
+		guides[p.x1] = pattern[0]
+		for c := p.x1 + 1; c < p.labelColumn(); c++ {
+			guides[c] = pattern[(c-p.x1-1)%len(pattern)]
+		}
+	}
+	return guides
+}
+
+// fillRune returns the rune for an otherwise empty column x
+// of this node's row. Columns outside of the guides are filled
+// with the first rune of the padding when the padding is that
+// rune repeated (e.g., "   "), or with spaces when the padding
+// mixes runes and only makes sense as a guide.
+func (n *Node) fillRune(guides map[int]rune, x int) rune {
+	if r, ok := guides[x]; ok {
+		return r
+	}
+	pad := n.padRune()
+	if strings.Trim(n.drawPadding, string(pad)) != "" {
+		return ' '
+	}
+	return pad
 }
 
 // maybe we could handle chars with greater font width later
@@ -464,34 +1862,120 @@ func (n *Node) shiftAllRight(amount int) {
 // DrawOptions takes a DrawInput struct with desired parameters
 // and returns the tree formatted string.
 func (n *Node) DrawOptions(di *DrawInput) (rendering string) {
-	if di.Padding != "" {
-		n.SetPaddingAll(di.Padding)
+	var pre strings.Builder
+	n.DrawInto(&pre, di)
+	rendering = pre.String()
+	return rendering
+}
+
+// DrawInto is like DrawOptions but appends the drawing to b,
+// so reports can be assembled in a single builder
+func (n *Node) DrawInto(b *strings.Builder, di *DrawInput) {
+	lines, trailer := n.drawLines(di)
+	for _, line := range lines {
+		b.WriteString(line.text)
+		b.WriteString("\n")
+	}
+	b.WriteString(trailer)
+}
+
+// DrawLines is like DrawOptions but returns the lines of the
+// drawing as a slice, without the final empty element that
+// splitting the output of DrawOptions on "\n" leaves
+func (n *Node) DrawLines(di *DrawInput) []string {
+	lines, trailer := n.drawLines(di)
+	text := make([]string, 0, len(lines))
+	for _, line := range lines {
+		text = append(text, line.text)
+	}
+	if trailer != "" {
+		text = append(text, strings.Split(strings.TrimSuffix(trailer, "\n"), "\n")...)
+	}
+	return text
+}
+
+// drawnLine is one line of a drawing along with the node it
+// belongs to, if any
+type drawnLine struct {
+	node *Node
+	text string
+	row  *rrow // nil for lines drawn straight to text
+}
+
+// rowLine returns the line for a rendered row
+func rowLine(node *Node, row *rrow) drawnLine {
+	return drawnLine{node, string(row.toRunes()), row}
+}
+
+// drawLines renders the rows of the drawing in order. Anything
+// drawn after the rows that doesn't belong to a node (legend
+// and ruler) is returned as the trailer.
+func (n *Node) drawLines(di *DrawInput) (lines []drawnLine, trailer string) {
+	di = n.withReduced(di)
+	if di.Isolated {
+		return n.drawIsolated(di)
+	}
+	if di.ChainInline {
+		return n.drawChained(di)
+	}
+	if di.SummarizeDeeperThan > 0 {
+		return n.drawSummarized(di)
+	}
+	// rows are rendered once we know which are in the viewport
+	bmp := make(map[int]func() drawnLine)
+	width := n.layoutWidth(di) // also sets key properties of nodes
+	if di.MetaColumn {
+		// measured once here since every label is trimmed to leave room for it
+		sub := *di
+		sub.metaWidth = n.maxMetaWidth(di)
+		di = &sub
+	}
+	if shift := n.minLabelShift(di); shift > 0 {
+		n.shiftAllRight(shift)
+		width += shift
+	}
+	if di.FixedWidth > 0 {
+		width = di.lastColumn(di.FixedWidth)
+	} else if di.MaxWidth > 0 && width+1 > di.MaxWidth {
+		if !di.footnoted {
+			return n.drawFootnoted(di)
+		}
+		// couldn't split any further so fall back to trimming
+		width = di.lastColumn(di.MaxWidth)
 	}
-	n.relateAsRoot() // set key properties of nodes
-	bmp := make(map[int][]rune)
-	width := n.getDescMaxWidth()
 	if di.Border {
-		width += 3
 		n.shiftAllRight(2)
 	}
 	desc := n.GetAllDescendents()
 	n.setTerminalDimensions()
-	if n.terminalWidth < width {
+	// a zero width means stdout is not a terminal, in which
+	// case nothing limits the width
+	if di.FixedWidth == 0 && n.terminalWidth > 0 && n.terminalWidth < width {
 		width = n.terminalWidth - 5
 	}
-	// draw root first
-	bmp[0] = n.render(width, di.Border).toRunes()
+	// draw root first, rows are keyed by twice the node's position
+	// to leave room for the connector rows of the stacked layout
+	if n.visible(di) {
+		if di.ShowParentStub && n.original().parent != nil {
+			bmp[-1] = func() drawnLine { return rowLine(nil, n.renderStub(width, di.Border)) }
+		}
+		bmp[0] = func() drawnLine { return rowLine(n, n.render(width, di)) }
+	}
 	// now draw descendents
-	for i := 1; i <= len(desc); i++ {
-		cn := desc[i-1]
+	for _, cn := range desc {
+		if !cn.visible(di) {
+			continue
+		}
 		cn.setFontWidth()
-		bmp[i] = cn.render(width, di.Border).toRunes()
+		// relate numbered the nodes in the order they are drawn
+		i := cn.index
+		if di.Stacked && !cn.isRoot {
+			bmp[i*2-1] = func() drawnLine { return rowLine(cn, cn.renderConnector(width, di)) }
+		}
+		bmp[i*2] = func() drawnLine { return rowLine(cn, cn.render(width, di)) }
 	}
-	// build string
-	var pre strings.Builder
 	if di.Border {
-		pre.Write([]byte(genTopBorder(width)))
-		pre.Write([]byte("\n"))
+		lines = append(lines, drawnLine{text: genTopBorder(width)})
 	}
 	// order our map
 	keys := make([]int, 0)
@@ -499,52 +1983,296 @@ func (n *Node) DrawOptions(di *DrawInput) (rendering string) {
 		keys = append(keys, k)
 	}
 	sort.Ints(keys)
-	for _, k := range keys {
-		line := bmp[k]
-		pre.Write([]byte(string(line)))
-		pre.Write([]byte("\n"))
+	if di.Inverted {
+		for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+			keys[i], keys[j] = keys[j], keys[i]
+		}
+	}
+	top, bottom := di.viewport(len(keys))
+	for i, k := range keys[top:bottom] {
+		line := bmp[k]()
+		if di.Inverted {
+			// label rows have even keys, leave their labels alone
+			end := width
+			if k%2 == 0 {
+				end = line.node.labelColumn() - 1
+			}
+			line.row.flipVertical(end)
+			line = rowLine(line.node, line.row)
+		}
+		// stripe by position in the whole tree so the stripes
+		// don't flip while scrolling
+		if di.Zebra && (top+i)%2 == 1 {
+			line.row.setBackground(di.zebraColor())
+			line = rowLine(line.node, line.row)
+		}
+		lines = append(lines, line)
 	}
 	if di.Border {
-		pre.Write([]byte(genBottomBorder(width)))
-		pre.Write([]byte("\n"))
+		lines = append(lines, drawnLine{text: genBottomBorder(width)})
+	}
+	if len(di.Legend) > 0 {
+		trailer += drawLegend(di.Legend)
 	}
 	if di.Debug {
-		pre.Write([]byte(drawRuler(width)))
+		trailer += drawRuler(width, di.RulerInterval)
 	}
-	rendering = pre.String()
-	return rendering
+	return lines, trailer
 }
 
-// drawRuler adds a ruler with column identifiers
-// every 5 ticks. It tries to keep labels lined
-// up with tick marks
-func drawRuler(maxWidth int) (ruler string) {
-	ruler += "\n"
-	for i := 0; i <= maxWidth; i++ {
-		if i%5 == 0 {
-			ruler += "|"
-		} else {
-			ruler += "."
+// lastColumn returns the last column of a drawing that is
+// columns wide, keeping it at least 2 columns wide with a
+// border so there's room for both of its sides
+func (di *DrawInput) lastColumn(columns int) int {
+	if di.Border && columns < 2 {
+		return 1
+	}
+	return columns - 1
+}
+
+// zebraColor returns the ZebraColor or its default
+func (di *DrawInput) zebraColor() color.Attribute {
+	if di.ZebraColor == 0 {
+		return color.BgHiBlack
+	}
+	return di.ZebraColor
+}
+
+// viewport returns the range of the rows drawn out of count
+// rows given ViewportTop and ViewportHeight
+func (di *DrawInput) viewport(count int) (top, bottom int) {
+	if di.ViewportHeight <= 0 {
+		return 0, count
+	}
+	top = di.ViewportTop
+	if top < 0 {
+		top = 0
+	}
+	if top > count {
+		top = count
+	}
+	bottom = top + di.ViewportHeight
+	if bottom > count {
+		bottom = count
+	}
+	return top, bottom
+}
+
+// drawIsolated draws a copy of this tree, mapping the lines
+// back to the nodes of this tree
+func (n *Node) drawIsolated(di *DrawInput) (lines []drawnLine, trailer string) {
+	sub := *di
+	sub.Isolated = false
+	return n.drawCopy(n.linkedClone(), &sub)
+}
+
+// drawCopy draws view, a copy of this tree made with
+// linkedCopy, in its place: the nodes of view are indexed and
+// given a parent stub like the nodes of this tree they copy,
+// and the lines are mapped back to the nodes of this tree.
+// Nodes added to view, which copy none, are drawn as they are.
+func (n *Node) drawCopy(view *Node, di *DrawInput) (lines []drawnLine, trailer string) {
+	lines, trailer = view.drawLines(n.withReduced(di))
+	toOriginals(lines)
+	return lines, trailer
+}
+
+// original returns the node this node is drawn in place of
+// when it is part of a copy drawn by drawCopy, or itself
+func (n *Node) original() *Node {
+	orig := n
+	for orig.source != nil {
+		orig = orig.source
+	}
+	return orig
+}
+
+// toOriginals replaces the nodes of lines drawn from a copy
+// made with linkedCopy by the nodes they copy, or nil for the
+// nodes added to the copy
+func toOriginals(lines []drawnLine) {
+	for i := range lines {
+		if lines[i].node != nil {
+			lines[i].node = lines[i].node.source
+		}
+	}
+}
+
+// RenderedWidth returns the number of columns of the widest
+// row drawn by DrawOptions(di), border included and color
+// sequences ignored. The legend and ruler are not measured.
+func (n *Node) RenderedWidth(di *DrawInput) (width int) {
+	lines, _ := n.drawLines(di)
+	for _, line := range lines {
+		if w := VisibleWidth(line.text); w > width {
+			width = w
+		}
+	}
+	return width
+}
+
+// minLabelShift returns how many columns DrawInput.MinLabelColumn
+// moves the tree right, must be called after relate
+func (n *Node) minLabelShift(di *DrawInput) int {
+	if di.MinLabelColumn <= 0 {
+		return 0
+	}
+	first := -1
+	n.Walk(func(node *Node) bool {
+		if col := node.labelColumn(); node.visible(di) && (first < 0 || col < first) {
+			first = col
+		}
+		return true
+	})
+	if di.Border {
+		first += 2
+	}
+	if shift := di.MinLabelColumn - first; first >= 0 && shift > 0 {
+		return shift
+	}
+	return 0
+}
+
+// ColumnBudget returns the column the labels of each depth
+// (relative to this node) start at when drawn with di,
+// counted from zero and border included. When the nodes of a
+// depth start at different columns, e.g. because of per-node
+// padding, the leftmost is returned. Depths that aren't drawn
+// are left out. The columns are those of the regular layout,
+// before MaxWidth splits the tree.
+func (n *Node) ColumnBudget(di *DrawInput) map[int]int {
+	di = n.withReduced(di)
+	n.layoutWidth(di) // relates the tree
+	offset := n.minLabelShift(di)
+	if di.Border {
+		offset += 2
+	}
+	budget := make(map[int]int)
+	n.Walk(func(node *Node) bool {
+		if !node.visible(di) {
+			return true
+		}
+		col := node.labelColumn() + offset
+		if start, ok := budget[node.rdepth]; !ok || col < start {
+			budget[node.rdepth] = col
+		}
+		return true
+	})
+	return budget
+}
+
+// RenderedLine is one line of a drawing and the node that
+// produced it. Node is nil for lines that don't belong to a
+// node, like borders or the parent stub.
+type RenderedLine struct {
+	Node *Node
+	Line string
+}
+
+// ToSlice returns the lines of DrawOptions(di) paired with
+// the nodes that produced them, in output order. The legend
+// and ruler are not included.
+func (n *Node) ToSlice(di *DrawInput) []RenderedLine {
+	lines, _ := n.drawLines(di)
+	rendered := make([]RenderedLine, len(lines))
+	for i, line := range lines {
+		rendered[i] = RenderedLine{Node: line.node, Line: line.text}
+	}
+	return rendered
+}
+
+// RenderRowIndex returns the node drawn on each row of the
+// output of DrawOptions(di), keyed by the zero based row.
+// Connector rows of the stacked layout belong to the node
+// below them. Border, stub, legend and ruler rows are not
+// in the map.
+func (n *Node) RenderRowIndex(di *DrawInput) map[int]*Node {
+	lines, _ := n.drawLines(di)
+	index := make(map[int]*Node)
+	for i, line := range lines {
+		if line.node != nil {
+			index[i] = line.node
+		}
+	}
+	return index
+}
+
+// LogPrefix returns the connectors drawn before this node's
+// label when its whole tree is drawn with Draw, e.g. "│   ├── ",
+// so other line oriented output can be aligned under the tree.
+// The root of the tree has no prefix.
+func (n *Node) LogPrefix() string {
+	if n == nil {
+		return ""
+	}
+	root := n
+	for root.parent != nil {
+		root = root.parent
+	}
+	lines, _ := root.drawLines(&DrawInput{})
+	for _, line := range lines {
+		if line.node != n || line.row == nil {
+			continue
+		}
+		var b strings.Builder
+		for x := 0; x < n.labelColumn(); x++ {
+			b.WriteRune(line.row.contents[x])
 		}
+		return b.String()
 	}
-	ruler += "\n"
-	var skipCount int
+	return ""
+}
+
+// drawLegend renders a colored swatch followed by its
+// label for each entry, sorted by label
+func drawLegend(legend map[string]color.Attribute) string {
+	labels := make([]string, 0, len(legend))
+	for label := range legend {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	var b strings.Builder
+	for _, label := range labels {
+		b.WriteString(color.New(legend[label]).Sprint(swatch()))
+		b.WriteString(" " + label + "\n")
+	}
+	return b.String()
+}
+
+// drawRuler adds a ruler with a tick every interval columns
+// labeled with the column number. A label that would run into
+// the previous one is left out so the rest stay lined up with
+// their ticks.
+func drawRuler(maxWidth, interval int) (ruler string) {
+	if interval <= 0 {
+		interval = 5
+	}
+	ticks := make([]rune, maxWidth+1)
+	labels := make([]rune, maxWidth+1)
+	free := 0 // first column not taken by a label
 	for i := 0; i <= maxWidth; i++ {
-		if i%5 == 0 {
-			label := strconv.Itoa(i)
-			labelWidth := utf8.RuneCountInString(label)
-			skipCount += labelWidth - 1
-			ruler += label
-		} else {
-			if skipCount == 0 {
-				ruler += " "
+		ticks[i] = '.'
+		if labels[i] == 0 {
+			labels[i] = ' '
+		}
+		if i%interval != 0 {
+			continue
+		}
+		ticks[i] = '|'
+		if i < free {
+			continue
+		}
+		label := []rune(strconv.Itoa(i))
+		for j, r := range label {
+			if i+j < len(labels) {
+				labels[i+j] = r
 			} else {
-				skipCount--
+				labels = append(labels, r)
 			}
 		}
+		free = i + len(label) + 1
 	}
-	ruler += "\n"
-	return ruler
+	return "\n" + string(ticks) + "\n" + string(labels) + "\n"
 }
 
 func firstRuneChar(s string) (char string) {
@@ -555,16 +2283,32 @@ func firstRuneChar(s string) (char string) {
 	return " "
 }
 
+func stackChar() string {
+	return string(theme.Stack)
+}
+
+func swatch() string {
+	return "■"
+}
+
+func stubChar() rune {
+	return theme.Stub
+}
+
+func arrowHead() string {
+	return string(theme.Arrow)
+}
+
 func horos() string {
-	return "─"
+	return string(theme.Horizontal)
 }
 
 func sibCharLastS() string {
-	return "└"
+	return string(theme.LastBranch)
 }
 
 func sibCharS() string {
-	return "├"
+	return string(theme.Branch)
 }
 
 func cleanLineage(input []*Node) (output []*Node) {
@@ -578,20 +2322,51 @@ func cleanLineage(input []*Node) (output []*Node) {
 
 // relate is meant to be a recursive function passing knowledge about parent relationships
 // it sets node properties to be used later for drawing purposes
-func (n *Node) relate(count *counter, amSibling, amLastSibling, parentIsSibling, parentIsLastSibling bool, parent *Node) {
+func (n *Node) relate(di *DrawInput, count *counter, amSibling, amLastSibling, parentIsSibling, parentIsLastSibling bool, parent *Node, depth int) {
+	n.ensureInit()
 	n.index = count.get()
 	count.add()
+	n.label = n.genLabel(di, depth == 0)
+	n.rawLabel = n.drawsRaw(di, depth == 0)
+	n.labelPad = 0
+	n.drawPadding = n.padding
+	if di.Padding != "" {
+		n.drawPadding = extendPadding(expandTabs(di.Padding, di.TabWidth))
+	}
+	n.rdepth = depth
+	n.hidden = depth < di.MinDepth
+	n.isRoot = depth == di.MinDepth
 	n.amLastSibling = amLastSibling
 	n.amSibling = amSibling
 	n.parentIsLastSibling = parentIsLastSibling
 	n.parentIsSibling = parentIsSibling
-	size := len(n.children)
+	n.marked = n.branchesHere(di)
+	n.decorator = n.genDecorator(0, di)
+	n.pruned = false
+	children := n.orderedChildren()
+	if di.PruneEmpty {
+		children = nil
+		for _, child := range n.orderedChildren() {
+			if child.isEmpty() {
+				child.Walk(func(desc *Node) bool {
+					desc.pruned = true
+					return true
+				})
+				continue
+			}
+			children = append(children, child)
+		}
+	}
+	size := len(children)
 	if parent != nil {
-		if parent.isRoot {
+		if parent.isRoot || parent.hidden {
 			n.setx1(parent.x1)
 			n.parentIsRoot = true
+		} else if parent.marked {
+			// hang from the mark, just before the label
+			n.setx1(parent.labelColumn() - 2)
 		} else {
-			n.setx1(parent.x1 + utf8.RuneCountInString(n.padding) + 1)
+			n.setx1(parent.labelColumn())
 		}
 		n.lineage = make([]*Node, len(parent.lineage)+1)
 		for _, ancestor := range parent.lineage {
@@ -601,7 +2376,7 @@ func (n *Node) relate(count *counter, amSibling, amLastSibling, parentIsSibling,
 		}
 		n.lineage = append(n.lineage, parent)
 	}
-	for i, child := range n.children {
+	for i, child := range children {
 		as := true            // am sibling
 		als := false          // am last sibling
 		pis := amSibling      // parent is sibling
@@ -609,72 +2384,240 @@ func (n *Node) relate(count *counter, amSibling, amLastSibling, parentIsSibling,
 		if i == (size - 1) {  // last element
 			als = true
 		}
-		child.relate(count, as, als, pis, pils, n)
+		child.relate(di, count, as, als, pis, pils, n, depth+1)
+	}
+	if di.PadSiblingsToMax {
+		padLabels(children, di)
 	}
 	n.lineage = cleanLineage(n.lineage)
 }
 
-func (n *Node) dive(depth int) int {
-	if len(n.children) > 0 {
-		depth += 1
-		for _, child := range n.children {
-			var d int
-			if d = child.dive(depth); d > depth {
-				depth = d
-			}
+// padLabels pads the labels of siblings, once they are
+// related, to the width of the widest of them for
+// DrawInput.PadSiblingsToMax
+func padLabels(siblings []*Node, di *DrawInput) {
+	widest := 0
+	for _, sibling := range siblings {
+		if w := VisibleWidth(sibling.label); w > widest {
+			widest = w
 		}
 	}
-	return depth
+	fill := di.PadFill
+	if fill == 0 {
+		fill = ' '
+	}
+	for _, sibling := range siblings {
+		sibling.labelPad = widest - VisibleWidth(sibling.label)
+		sibling.label += strings.Repeat(string(fill), sibling.labelPad)
+		// the label grew so its end moves with it
+		sibling.setx1(sibling.x1)
+	}
 }
 
-func (n *Node) diveRetrieve(depth, desired int, col *collector) {
-	// if desired is -1 then we'll just set depth and
-	// add ourselves to collector
-	if desired == -1 {
-		nn := NewNode(n.contents)
-		nn.setPadding(n.padding)
-		nn.parent = n.parent
-		nn.children = append(nn.children, n.children...)
-		nn.depth = depth
-		col.add(nn)
+// isEmpty reports whether this node and all of its
+// descendents have empty contents
+func (n *Node) isEmpty() bool {
+	if n.contents != "" {
+		return false
 	}
-
-	// if this node's children are the desired depth then
-	// add them to the collector and return
-	if (depth+1 == desired) && (col != nil) && len(n.children) != 0 {
-		for _, c := range n.children {
-			col.add(c)
+	for _, child := range n.children {
+		if !child.isEmpty() {
+			return false
 		}
-		return
 	}
+	return true
+}
 
-	// otherwise, dig deeper
+func (n *Node) dive(depth int) int {
 	if len(n.children) > 0 {
 		depth += 1
 		for _, child := range n.children {
-			child.diveRetrieve(depth, desired, col)
+			var d int
+			if d = child.dive(depth); d > depth {
+				depth = d
+			}
 		}
 	}
+	return depth
 }
 
 // NumChildren returns the number of children
 // this node has
 func (n *Node) NumChildren() int {
+	if n == nil {
+		return 0
+	}
 	return len(n.children)
 }
 
+// IsLastSibling returns whether this node is the last
+// child of its parent. A node without a parent is
+// considered its own last sibling. Unlike the internal
+// drawing state this does not require a prior draw.
+func (n *Node) IsLastSibling() bool {
+	if n == nil {
+		return false
+	}
+	if n.parent == nil {
+		return true
+	}
+	return n.parent.children[len(n.parent.children)-1] == n
+}
+
+// SiblingCount returns the number of other children
+// the parent of this node has. A node without a parent
+// has no siblings.
+func (n *Node) SiblingCount() int {
+	if n == nil {
+		return 0
+	}
+	if n.parent == nil {
+		return 0
+	}
+	return len(n.parent.children) - 1
+}
+
 // GetGeneration gets all the children of the y'th
-// generation of this node
+// generation of this node. Negative values count up from
+// the deepest generation, so -1 returns the nodes at
+// Height() and -2 the generation above. In trees of uneven
+// depth the deepest generation only holds the nodes at the
+// very bottom, not every leaf. Generation 0 and counting up
+// past the first generation return nothing. The returned
+// pointers are the real nodes in the tree, in the order of
+// GetChild from left to right, so changes to them are reflected in later draws.
 func (n *Node) GetGeneration(y int) []*Node {
-	col := collector{}
-	var depth int
-	n.diveRetrieve(depth, y, &col)
-	return col.results
+	if y < 0 {
+		y = n.Height() + 1 + y
+	}
+	if y < 1 {
+		return nil
+	}
+	return n.generation(y)
+}
+
+// FilterGeneration removes each node of the y'th generation
+// of this node (see GetGeneration) for which keep returns
+// false. With promoteChildren the children of a removed node
+// take its place beneath its parent, in order, otherwise they
+// are removed along with it. Depths are updated and the
+// changes are journaled like RemoveChild and MoveTo.
+// Generations below 1 are left as is.
+func (n *Node) FilterGeneration(y int, keep func(*Node) bool, promoteChildren bool) {
+	for _, node := range n.GetGeneration(y) {
+		if keep(node) {
+			continue
+		}
+		parent := node.parent
+		at := parent.childIndex(node)
+		if promoteChildren {
+			for len(node.children) > 0 {
+				at++
+				node.children[0].moveTo(parent, at)
+			}
+		}
+		parent.RemoveChild(parent.childIndex(node))
+	}
+}
+
+// GetGenerationSorted returns the y'th generation of this
+// node, counted like GetGeneration, ordered by less without
+// reordering the tree itself. The returned pointers are always
+// the real nodes in the tree so changes to them are reflected
+// in later draws.
+func (n *Node) GetGenerationSorted(y int, less func(a, b *Node) bool) []*Node {
+	gen := append([]*Node(nil), n.GetGeneration(y)...)
+	sort.SliceStable(gen, func(i, j int) bool {
+		return less(gen[i], gen[j])
+	})
+	return gen
+}
+
+// SortStable sorts the children of this node and of all of
+// its descendents by contents so trees built in a changing
+// order, e.g. from a map, draw the same every time. Siblings
+// with the same contents are ordered by their own descendents
+// and then by id. The sort is not recorded by the journal.
+func (n *Node) SortStable() {
+	if n == nil {
+		return
+	}
+	summaries := make(map[*Node]string, len(n.children))
+	for _, child := range n.children {
+		child.SortStable()
+		summaries[child] = child.Summary()
+	}
+	n.drawOrder = nil
+	sort.SliceStable(n.children, func(i, j int) bool {
+		a, b := n.children[i], n.children[j]
+		if a.contents != b.contents {
+			return a.contents < b.contents
+		}
+		if summaries[a] != summaries[b] {
+			return summaries[a] < summaries[b]
+		}
+		return a.id < b.id
+	})
+}
+
+// CoalesceRepeats replaces each run of adjacent children with
+// the same contents and equal subtrees (compared like Summary)
+// with the first of them, labeled with the size of the run,
+// e.g. three "retry" leaves become one "retry (×3)". Repeats
+// that differ anywhere in their subtrees are left alone, and
+// the kept node keeps its own children. Descendents are
+// coalesced first, so repeats deeper in the tree are merged
+// before their parents are compared.
+func (n *Node) CoalesceRepeats() {
+	if n == nil {
+		return
+	}
+	for _, child := range n.children {
+		child.CoalesceRepeats()
+	}
+	for i := 0; i < len(n.children); i++ {
+		first := n.children[i]
+		summary := first.Summary()
+		count := 1
+		for i+1 < len(n.children) && n.children[i+1].Summary() == summary {
+			n.RemoveChild(i + 1)
+			count++
+		}
+		if count > 1 {
+			first.SetContents(fmt.Sprintf("%s (×%d)", first.contents, count))
+		}
+	}
+}
+
+// generation returns pointers to the real nodes that are
+// y generations beneath this node
+func (n *Node) generation(y int) []*Node {
+	if n == nil {
+		return nil
+	}
+	if y < 0 {
+		return nil
+	}
+	gen := []*Node{n}
+	for i := 0; i < y; i++ {
+		var next []*Node
+		for _, node := range gen {
+			next = append(next, node.children...)
+		}
+		gen = next
+	}
+	return gen
 }
 
 // MaxDepth returns the maximum depth of descendents
-// and child descendents
+// relative to this node, counted in edges. It is the
+// same value as Height and is kept for compatibility.
+// A node without children returns 0. Note that this
+// differs from GetDepth, which is absolute from the root.
 func (n *Node) MaxDepth() (maxDepth int) {
+	if n == nil {
+		return 0
+	}
 	for _, child := range n.children {
 		depth := 1
 		depth = child.dive(depth)
@@ -684,3 +2627,20 @@ func (n *Node) MaxDepth() (maxDepth int) {
 	}
 	return maxDepth
 }
+
+// Height returns the number of edges on the longest
+// path from this node down to a leaf. A leaf has a
+// height of 0 and a node with only leaf children has
+// a height of 1. For any node d beneath n,
+// d.GetDepth()-n.GetDepth() is at most n.Height().
+func (n *Node) Height() (height int) {
+	if n == nil {
+		return 0
+	}
+	for _, child := range n.children {
+		if h := child.Height() + 1; h > height {
+			height = h
+		}
+	}
+	return height
+}
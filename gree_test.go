@@ -3,8 +3,13 @@ package gree
 import (
 	"fmt"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+	"github.com/google/uuid"
 )
 
 func TestDrawSimple(t *testing.T) {
@@ -21,12 +26,15 @@ func TestDrawSimple(t *testing.T) {
 	expected := string(dat)
 	linesExpected := strings.Split(expected, "\n")
 	linesGot := strings.Split(got, "\n")
-	for i := 0; i >= len(linesExpected); i++ {
+	if len(linesGot) != len(linesExpected) {
+		t.Errorf("expected %d lines, got %d", len(linesExpected), len(linesGot))
+	}
+	for i := 0; i < len(linesExpected); i++ {
 		lineGot := ""
-		if len(linesGot) >= i {
+		if i < len(linesGot) {
 			lineGot = linesGot[i]
 		}
-		if linesExpected[i] != lineGot {
+		if linesExpected[i] != strings.TrimRight(lineGot, " ") {
 			t.Errorf("output does not match expected in testfile %s\n", testfile)
 			t.Errorf("line %d, expected '%s', got '%s'", i, linesExpected[i], lineGot)
 		}
@@ -80,7 +88,7 @@ func TestMaxWidth(t *testing.T) {
 	a.NewChild("child3").NewChild("grandchild1")
 	a.Draw()
 	expected := 18
-	got := a.getDescMaxWidth()
+	got := a.getDescMaxWidth(&DrawInput{})
 	if got != expected {
 		fmt.Println(a.Draw())
 		t.Errorf("expected %d, got %d", expected, got)
@@ -98,3 +106,2245 @@ func TestGetGeneration(t *testing.T) {
 		t.Errorf("expected %d, got %d", expected, len(got))
 	}
 }
+
+func TestGetGenerationRealNodes(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1").NewChild("grandchild1")
+	a.NewChild("child2")
+	for _, y := range []int{1, 2, -1} {
+		for _, node := range a.GetGeneration(y) {
+			node.SetContents(node.String() + "!")
+		}
+	}
+	got := strings.Split(a.Draw(), "\n")
+	expected := []string{
+		"root",
+		"├── child1!",
+		"│   └── grandchild1!!",
+		"└── child2!",
+	}
+	for i, e := range expected {
+		if strings.TrimRight(got[i], " ") != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+		}
+	}
+}
+
+func TestGetGenerationNegative(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1").NewChild("grandchild1")
+	a.NewChild("child2").NewChild("grandchild2").NewChild("greatgrandchild1")
+	tests := map[int][]string{
+		-1: {"greatgrandchild1"},
+		-2: {"grandchild1", "grandchild2"},
+		-3: {"child1", "child2"},
+		-4: nil,
+	}
+	for y, expected := range tests {
+		got := a.GetGeneration(y)
+		if len(got) != len(expected) {
+			t.Errorf("generation %d, expected %d nodes, got %d", y, len(expected), len(got))
+			continue
+		}
+		for i, e := range expected {
+			if got[i].String() != e {
+				t.Errorf("generation %d, expected '%s', got '%s'", y, e, got[i])
+			}
+		}
+	}
+}
+
+func TestBranchColor(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+	a := NewNode("root")
+	a.NewChild("child1").SetBranchColor(color.FgRed).NewChild("grandchild1")
+	a.NewChild("child2")
+	lines := strings.Split(a.Draw(), "\n")
+	red := color.New(color.FgRed)
+	expected := []string{
+		"root",
+		red.Sprint("├──") + " child1",
+		red.Sprint("│") + "   " + red.Sprint("└──") + " grandchild1",
+		"└── child2",
+	}
+	for i, e := range expected {
+		if strings.TrimRight(lines[i], " ") != e {
+			t.Errorf("line %d, expected '%q', got '%q'", i, e, lines[i])
+		}
+	}
+}
+
+func TestDepthRelativeTo(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	c := b.NewChild("grandchild1").NewChild("greatgrandchild1")
+	other := a.NewChild("child2")
+	if got, ok := c.DepthRelativeTo(b); !ok || got != 2 {
+		t.Errorf("expected 2 and true, got %d and %t", got, ok)
+	}
+	if got, ok := c.DepthRelativeTo(c); !ok || got != 0 {
+		t.Errorf("expected 0 and true, got %d and %t", got, ok)
+	}
+	if _, ok := c.DepthRelativeTo(other); ok {
+		t.Errorf("expected false for node not on path")
+	}
+}
+
+func TestDrawDepthWindow(t *testing.T) {
+	a := NewNode("root")
+	c := a.NewChild("child1")
+	c.NewChild("grandchild1").NewChild("greatgrandchild1").NewChild("hidden")
+	c.NewChild("grandchild2")
+	a.NewChild("child2").NewChild("grandchild3")
+	got := strings.Split(a.DrawOptions(&DrawInput{MinDepth: 2, MaxDepth: 3}), "\n")
+	expected := []string{
+		"grandchild1",
+		"└── greatgrandchild1",
+		"grandchild2",
+		"grandchild3",
+	}
+	if len(got) != len(expected)+1 {
+		t.Fatalf("expected %d lines, got %d", len(expected)+1, len(got))
+	}
+	for i, e := range expected {
+		if strings.TrimRight(got[i], " ") != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+		}
+	}
+}
+
+func TestSiblingQueries(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	c := a.NewChild("child2")
+	d := a.NewChild("child3")
+	if b.IsLastSibling() || c.IsLastSibling() || !d.IsLastSibling() {
+		t.Errorf("expected only child3 to be the last sibling")
+	}
+	if !a.IsLastSibling() || a.SiblingCount() != 0 {
+		t.Errorf("expected root to be its own last sibling with no siblings")
+	}
+	if got := c.SiblingCount(); got != 2 {
+		t.Errorf("expected 2 siblings, got %d", got)
+	}
+}
+
+func TestTabPadding(t *testing.T) {
+	build := func() *Node {
+		a := NewNode("root")
+		a.NewChild("child1").NewChild("grandchild1")
+		a.NewChild("child2")
+		return a
+	}
+	expected := build().DrawOptions(&DrawInput{Padding: "    "})
+	got := build().DrawOptions(&DrawInput{Padding: "\t"})
+	if got != expected {
+		t.Errorf("expected tab padding to expand to 4 spaces, got\n%s\nexpected\n%s", got, expected)
+	}
+	got = build().DrawOptions(&DrawInput{Padding: "\t", TabWidth: 2})
+	expected = build().DrawOptions(&DrawInput{Padding: "  "})
+	if got != expected {
+		t.Errorf("expected tab padding to expand to 2 spaces, got\n%s\nexpected\n%s", got, expected)
+	}
+	if err := build().SetPaddingAll("\t"); err == nil {
+		t.Errorf("expected error setting tab padding directly")
+	}
+}
+
+func TestMinimumPadding(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1").NewChild("grandchild1")
+	a.NewChild("child2")
+	got := strings.Split(a.DrawOptions(&DrawInput{Padding: " "}), "\n")
+	expected := []string{
+		"root",
+		"├─ child1",
+		"│  └─ grandchild1",
+		"└─ child2",
+	}
+	for i, e := range expected {
+		if strings.TrimRight(got[i], " ") != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+		}
+	}
+}
+
+func TestSetIDGenerator(t *testing.T) {
+	var next int
+	SetIDGenerator(func() string {
+		next++
+		return fmt.Sprintf("node-%d", next)
+	})
+	defer SetIDGenerator(nil)
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	if a.GetID() != "node-1" || b.GetID() != "node-2" {
+		t.Errorf("expected sequential ids, got '%s' and '%s'", a.GetID(), b.GetID())
+	}
+	SetIDGenerator(nil)
+	if c := NewNode("random"); c.GetID() == "node-3" || c.GetID() == "" {
+		t.Errorf("expected random id after reset, got '%s'", c.GetID())
+	}
+}
+
+func TestParentStub(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	b.NewChild("grandchild1")
+	got := strings.Split(b.DrawOptions(&DrawInput{ShowParentStub: true}), "\n")
+	expected := []string{
+		"┆",
+		"child1",
+		"└── grandchild1",
+	}
+	for i, e := range expected {
+		if strings.TrimRight(got[i], " ") != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+		}
+	}
+	if strings.Contains(a.DrawOptions(&DrawInput{ShowParentStub: true}), "┆") {
+		t.Errorf("expected no stub for a node without a parent")
+	}
+}
+
+func TestColorWhere(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1").NewChild("grandchild1")
+	a.NewChild("child2")
+	got := a.ColorWhere(func(n *Node) bool {
+		return n.NumChildren() == 0
+	}, color.FgGreen)
+	if got != 2 {
+		t.Errorf("expected 2 leaves colored, got %d", got)
+	}
+	if a.colored || !a.GetChild(1).colored {
+		t.Errorf("expected only leaves to be colored")
+	}
+}
+
+func TestHeight(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	c := b.NewChild("grandchild1")
+	a.NewChild("child2")
+	for _, tc := range []struct {
+		node     *Node
+		expected int
+	}{
+		{a, 2},
+		{b, 1},
+		{c, 0},
+	} {
+		if got := tc.node.Height(); got != tc.expected {
+			t.Errorf("expected height of '%s' to be %d, got %d", tc.node, tc.expected, got)
+		}
+		if got := tc.node.MaxDepth(); got != tc.expected {
+			t.Errorf("expected MaxDepth of '%s' to be %d, got %d", tc.node, tc.expected, got)
+		}
+	}
+	if got := c.GetDepth(); got != 2 {
+		t.Errorf("expected absolute depth 2, got %d", got)
+	}
+}
+
+func TestArrowStyle(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1").NewChild("grandchild1")
+	a.NewChild("child2")
+	got := strings.Split(a.DrawOptions(&DrawInput{ArrowStyle: true}), "\n")
+	expected := []string{
+		"root",
+		"├─▶ child1",
+		"│   └─▶ grandchild1",
+		"└─▶ child2",
+	}
+	for i, e := range expected {
+		if strings.TrimRight(got[i], " ") != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+		}
+	}
+}
+
+func TestAddChildReparents(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	c := a.NewChild("child2")
+	x := b.NewChild("moving")
+	x.NewChild("passenger")
+	c.AddChild(x)
+	if b.NumChildren() != 0 {
+		t.Errorf("expected old parent to have 0 children, got %d", b.NumChildren())
+	}
+	if c.NumChildren() != 1 || c.GetChild(0) != x || x.parent != c {
+		t.Errorf("expected node to be attached to the new parent only")
+	}
+	if got := x.GetChild(0).GetDepth(); got != 3 {
+		t.Errorf("expected passenger depth 3, got %d", got)
+	}
+	if got := len(a.GetAllDescendents()); got != 4 {
+		t.Errorf("expected 4 descendents, got %d", got)
+	}
+	a.EnableJournal()
+	if got := a.AddChild(b); got != b || a.GetChild(0) != b || a.NumChildren() != 2 {
+		t.Errorf("expected adding an existing child to keep it in place")
+	}
+	if err := a.Undo(); err == nil {
+		t.Errorf("expected adding an existing child not to be journaled")
+	}
+}
+
+func TestSetColors(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+	a := NewNode("root").SetColors(color.FgRed, color.BgBlue)
+	got := strings.Split(a.Draw(), "\n")[0]
+	expected := color.New(color.FgRed, color.BgBlue).Sprint("root")
+	if strings.TrimRight(got, " ") != expected {
+		t.Errorf("expected '%q', got '%q'", expected, got)
+	}
+	// one sequence to set the colors and one to reset them
+	if strings.Count(got, "\x1b[") != 2 {
+		t.Errorf("expected a single escape sequence, got '%q'", got)
+	}
+}
+
+func TestLegend(t *testing.T) {
+	build := func() *Node {
+		a := NewNode("root")
+		a.NewChild("child1").SetColorRed()
+		return a
+	}
+	plain := build().DrawOptions(&DrawInput{Border: true})
+	got := build().DrawOptions(&DrawInput{
+		Border: true,
+		Legend: map[string]color.Attribute{
+			"warnings": color.FgYellow,
+			"a much longer label than the tree is wide": color.FgRed,
+		},
+	})
+	expected := plain + "■ a much longer label than the tree is wide\n■ warnings\n"
+	if got != expected {
+		t.Errorf("expected sorted legend below the unchanged tree, got\n%s\nexpected\n%s", got, expected)
+	}
+}
+
+func TestMetaColumn(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1").SetMeta("4.0K").NewChild("grandchild1").SetMeta("12")
+	a.NewChild("child2").SetColorRed().SetMeta("100K")
+	got := strings.Split(a.DrawOptions(&DrawInput{MetaColumn: true, Border: true}), "\n")
+	expected := []string{
+		"┌─────────────────────────┐",
+		"│ root                    │",
+		"│ ├── child1          4.0K│",
+		"│ │   └── grandchild1   12│",
+		"│ └── child2          100K│",
+		"└─────────────────────────┘",
+	}
+	for i, e := range expected {
+		if got[i] != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+		}
+	}
+}
+
+func TestMetaColumnTrimmed(t *testing.T) {
+	a := NewNode("root").SetMeta("目录")
+	a.NewChild("a-rather-long-name").SetMeta("4.0K")
+	tests := []struct {
+		di       *DrawInput
+		expected []string
+	}{
+		{&DrawInput{MetaColumn: true, FixedWidth: 20}, []string{
+			"root            目录",
+			"└── a-rather... 4.0K",
+		}},
+		{&DrawInput{MetaColumn: true, FixedWidth: 22, Border: true}, []string{
+			"┌────────────────────┐",
+			"│ root           目录│",
+			"│ └── a-rathe... 4.0K│",
+			"└────────────────────┘",
+		}},
+		{&DrawInput{MetaColumn: true}, []string{
+			"root                   目录",
+			"└── a-rather-long-name 4.0K",
+		}},
+	}
+	for _, test := range tests {
+		got := a.DrawLines(test.di)
+		if len(got) != len(test.expected) {
+			t.Errorf("expected %d lines, got %q", len(test.expected), got)
+			continue
+		}
+		for i, e := range test.expected {
+			if got[i] != e {
+				t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+			}
+		}
+	}
+}
+
+func TestGetGenerationSorted(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1").NewChild("zebra")
+	a.NewChild("child2").NewChild("apple")
+	a.NewChild("child3").NewChild("mango")
+	got := a.GetGenerationSorted(2, func(x, y *Node) bool {
+		return x.String() < y.String()
+	})
+	var names []string
+	for _, node := range got {
+		names = append(names, node.String())
+	}
+	if strings.Join(names, ",") != "apple,mango,zebra" {
+		t.Errorf("expected sorted generation, got '%s'", strings.Join(names, ","))
+	}
+	if got[0] != a.GetChild(1).GetChild(0) {
+		t.Errorf("expected real node pointers to be returned")
+	}
+	if a.GetChild(0).GetChild(0).String() != "zebra" {
+		t.Errorf("expected tree order to be unchanged")
+	}
+	byContents := func(x, y *Node) bool { return x.String() < y.String() }
+	if got := a.GetGenerationSorted(-1, byContents); len(got) != 3 || got[0].String() != "apple" {
+		t.Errorf("expected -1 to count up from the deepest generation, got %v", got)
+	}
+	if got := a.GetGenerationSorted(0, byContents); len(got) != 0 {
+		t.Errorf("expected nothing for generation 0 like GetGeneration, got %v", got)
+	}
+}
+
+func TestStacked(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1").NewChild("grandchild1")
+	a.NewChild("child2")
+	got := strings.Split(a.DrawOptions(&DrawInput{Stacked: true}), "\n")
+	expected := []string{
+		"root",
+		"├───┐",
+		"│   child1",
+		"│   └───┐",
+		"│       grandchild1",
+		"└───┐",
+		"    child2",
+	}
+	if len(got) != len(expected)+1 {
+		t.Fatalf("expected %d lines, got %d", len(expected)+1, len(got))
+	}
+	for i, e := range expected {
+		if strings.TrimRight(got[i], " ") != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+		}
+	}
+}
+
+func TestResetLayout(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	c := b.NewChild("grandchild1")
+	a.DrawOptions(&DrawInput{Border: true})
+	a.ResetLayout()
+	for _, node := range []*Node{a, b, c} {
+		if node.x1 != 0 || node.x2 != 0 || node.lineage != nil || node.index != 0 || node.isRoot {
+			t.Errorf("expected render state of '%s' to be reset", node)
+		}
+	}
+	if a.count.get() != 0 {
+		t.Errorf("expected counter to be reset, got %d", a.count.get())
+	}
+	descendents := a.GetAllDescendents()
+	if len(descendents) != 2 || descendents[0] != b || descendents[1] != c {
+		t.Errorf("expected descendents in drawing order after reset")
+	}
+}
+
+func TestGetAllDescendentsOrder(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	c := b.NewChild("grandchild1")
+	d := a.NewChild("child2")
+	e := d.NewChild("grandchild2")
+	expected := []*Node{b, c, d, e}
+	check := func(when string) {
+		got := a.GetAllDescendents()
+		if len(got) != len(expected) {
+			t.Fatalf("%s, expected %d descendents, got %d", when, len(expected), len(got))
+		}
+		for i := range expected {
+			if got[i] != expected[i] {
+				t.Errorf("%s, expected '%s' at %d, got '%s'", when, expected[i], i, got[i])
+			}
+		}
+	}
+	check("before drawing")
+	a.SetDrawOrder([]int{1, 0})
+	a.Draw()
+	check("after drawing in another order")
+	b.DrawOptions(&DrawInput{})
+	check("after drawing a subtree")
+}
+
+func TestDrawTwice(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1").NewChild("grandchild1")
+	a.NewChild("child2")
+	first := a.Draw()
+	var firstIndexes []int
+	for _, desc := range a.GetAllDescendents() {
+		firstIndexes = append(firstIndexes, desc.index)
+	}
+	second := a.Draw()
+	if first != second {
+		t.Errorf("expected identical output, got\n%s\nthen\n%s", first, second)
+	}
+	for i, desc := range a.GetAllDescendents() {
+		if desc.index != firstIndexes[i] {
+			t.Errorf("expected index %d for '%s', got %d", firstIndexes[i], desc, desc.index)
+		}
+	}
+	if len(a.lineage) != 1 {
+		t.Errorf("expected root lineage not to grow, got %d", len(a.lineage))
+	}
+}
+
+func TestClone(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1").SetColorRed().NewChild("grandchild1")
+	a.NewChild("child2")
+	fresh := a.Clone()
+	kept := a.CloneKeepIDs()
+	if fresh.Draw() != a.Draw() || kept.Draw() != a.Draw() {
+		t.Errorf("expected clones to draw the same as the original")
+	}
+	originals := append([]*Node{a}, a.GetAllDescendents()...)
+	for i, node := range append([]*Node{kept}, kept.GetAllDescendents()...) {
+		if node == originals[i] || node.GetID() != originals[i].GetID() {
+			t.Errorf("expected a copy of '%s' with the same id", originals[i])
+		}
+	}
+	for i, node := range append([]*Node{fresh}, fresh.GetAllDescendents()...) {
+		if node.GetID() == originals[i].GetID() {
+			t.Errorf("expected a new id for the copy of '%s'", originals[i])
+		}
+	}
+	if got := kept.GetChild(0).GetChild(0).GetDepth(); got != 2 {
+		t.Errorf("expected depth 2 in the copy, got %d", got)
+	}
+}
+
+func TestShowStatus(t *testing.T) {
+	a := NewNode("build")
+	a.NewChild("compile").SetStatus(Success)
+	a.NewChild("test").SetStatus(Failure)
+	a.NewChild("deploy").SetStatus(Skipped)
+	got := strings.Split(a.DrawOptions(&DrawInput{
+		ShowStatus:  true,
+		StatusTheme: map[Status]StatusStyle{Skipped: {Glyph: "-"}},
+	}), "\n")
+	expected := []string{
+		"build",
+		"├── ✓ compile",
+		"├── ✗ test",
+		"└── - deploy",
+	}
+	for i, e := range expected {
+		if strings.TrimRight(got[i], " ") != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+		}
+	}
+}
+
+func TestColorStatus(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+	a := NewNode("build")
+	a.NewChild("compile").SetStatus(Success)
+	got := strings.Split(a.DrawOptions(&DrawInput{ShowStatus: true, ColorStatus: true}), "\n")
+	expected := "└── " + color.New(color.FgGreen).Sprint("✓ compile")
+	if strings.TrimRight(got[1], " ") != expected {
+		t.Errorf("expected '%q', got '%q'", expected, got[1])
+	}
+}
+
+func TestMaxWidthFootnotes(t *testing.T) {
+	a := NewNode("root")
+	c := a.NewChild("child1")
+	c.NewChild("grandchild1").NewChild("greatgrandchild1")
+	a.NewChild("child2")
+	got := a.DrawOptions(&DrawInput{MaxWidth: 20})
+	expected := []string{
+		"root",
+		"├── child1 [1]",
+		"└── child2",
+		"",
+		"[1] child1",
+		"└── grandchild1 [2]",
+		"",
+		"[2] grandchild1",
+		"└── greatgrandchild1",
+		"",
+	}
+	lines := strings.Split(got, "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got %d:\n%s", len(expected), len(lines), got)
+	}
+	for i, e := range expected {
+		if strings.TrimRight(lines[i], " ") != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, lines[i])
+		}
+		if utf8.RuneCountInString(lines[i]) > 20 {
+			t.Errorf("line %d is wider than 20 columns: '%s'", i, lines[i])
+		}
+	}
+	if c.GetChild(0).NumChildren() != 1 || c.GetChild(0).String() != "grandchild1" {
+		t.Errorf("expected original tree to be untouched")
+	}
+}
+
+func TestDrawPaddingIsRenderOnly(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1").NewChild("grandchild1")
+	a.NewChild("child2")
+	before := a.Draw()
+	wide := a.DrawOptions(&DrawInput{Padding: "      "})
+	if !strings.Contains(wide, "└───── grandchild1") {
+		t.Errorf("expected wide padding to be rendered, got\n%s", wide)
+	}
+	if after := a.Draw(); after != before {
+		t.Errorf("expected original padding to be kept, got\n%s\nexpected\n%s", after, before)
+	}
+	for _, node := range a.GetAllDescendents() {
+		if node.padding != "   " {
+			t.Errorf("expected padding of '%s' to be unchanged, got '%s'", node, node.padding)
+		}
+	}
+}
+
+func TestSetDecorator(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("link").SetDecorator("╫═══>", "╙═══>").NewChild("target")
+	a.NewChild("file")
+	got := strings.Split(a.DrawOptions(&DrawInput{Border: true}), "\n")
+	expected := []string{
+		"┌─────────────────┐",
+		"│ root            │",
+		"│ ╫═══> link      │",
+		"│ │     └── target│",
+		"│ └── file        │",
+		"└─────────────────┘",
+	}
+	for i, e := range expected {
+		if got[i] != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+		}
+	}
+}
+
+func TestDrawBorderColored(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+	a := NewNode("root").SetColorMagenta()
+	b := a.NewChild("child1").SetColor(color.BgBlue).SetColor(color.FgHiRed)
+	b.NewChild("grandchild1").SetColorYellow()
+	b.NewChild("grandchild2")
+	a.NewChild("child2").SetColors(color.FgGreen, color.Bold).NewChild("grandchild3").SetColorRed()
+	got := a.DrawOptions(&DrawInput{Border: true})
+	testfile := "./testdata/TestDrawBorderColored.txt"
+	dat, err := os.ReadFile(testfile)
+	if err != nil {
+		t.Fatalf("error pulling expected from file '%s', error '%s'\n", testfile, err.Error())
+	}
+	if got != string(dat) {
+		t.Errorf("output does not match expected in testfile %s, got\n%s", testfile, got)
+	}
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	for i, line := range lines {
+		if VisibleWidth(line) != VisibleWidth(lines[0]) {
+			t.Errorf("line %d has visible width %d, expected %d", i, VisibleWidth(line), VisibleWidth(lines[0]))
+		}
+	}
+}
+
+func TestGuidePadding(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	b.NewChild("grandchild1").NewChild("greatgrandchild1")
+	b.NewChild("grandchild2")
+	a.NewChild("child2").NewChild("grandchild3")
+	got := strings.Split(a.DrawOptions(&DrawInput{Padding: "·· "}), "\n")
+	expected := []string{
+		"root",
+		"├── child1",
+		"│·· ├── grandchild1",
+		"│·· │·· └── greatgrandchild1",
+		"│·· └── grandchild2",
+		"└── child2",
+		"··· └── grandchild3",
+	}
+	for i, e := range expected {
+		if strings.TrimRight(got[i], " ") != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+		}
+	}
+}
+
+func TestPadSiblingsToMax(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("a")
+	b := a.NewChild("longchild")
+	b.NewChild("x")
+	b.NewChild("yyy")
+	a.NewChild("bb")
+	got := strings.Split(a.DrawOptions(&DrawInput{Border: true, PadSiblingsToMax: true, PadFill: '.'}), "\n")
+	expected := []string{
+		"┌──────────────┐",
+		"│ root         │",
+		"│ ├── a........│",
+		"│ ├── longchild│",
+		"│ │   ├── x..  │",
+		"│ │   └── yyy  │",
+		"│ └── bb.......│",
+		"└──────────────┘",
+	}
+	for i, e := range expected {
+		if got[i] != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+		}
+	}
+}
+
+func TestPadSiblingsToMaxCallsOnce(t *testing.T) {
+	a := NewNode("root")
+	for i := 0; i < 5; i++ {
+		a.NewChild(strings.Repeat("x", i+1))
+	}
+	calls := 0
+	suffix := func(*Node) string {
+		calls++
+		return ""
+	}
+	a.DrawOptions(&DrawInput{SuffixFunc: suffix})
+	plain := calls
+	calls = 0
+	a.DrawOptions(&DrawInput{SuffixFunc: suffix, PadSiblingsToMax: true})
+	if calls != plain {
+		t.Errorf("expected SuffixFunc to be called %d times like without padding, got %d", plain, calls)
+	}
+}
+
+func TestPadSiblingsToMaxUncolored(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+	a := NewNode("root")
+	a.NewChild("a").SetColor(color.BgBlue)
+	a.NewChild("abc")
+	got := strings.Split(a.DrawOptions(&DrawInput{PadSiblingsToMax: true}), "\n")
+	if !strings.Contains(got[1], color.New(color.BgBlue).Sprint("a")+"  ") {
+		t.Errorf("expected padding to be left uncolored, got %q", got[1])
+	}
+}
+
+func TestRenderRowIndex(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	g := b.NewChild("grandchild1")
+	c := a.NewChild("child2")
+	got := a.RenderRowIndex(&DrawInput{Border: true, Stacked: true})
+	expected := map[int]*Node{1: a, 2: b, 3: b, 4: g, 5: g, 6: c, 7: c}
+	if len(got) != len(expected) {
+		t.Errorf("expected %d rows, got %d", len(expected), len(got))
+	}
+	for row, node := range expected {
+		if got[row] != node {
+			t.Errorf("row %d, expected '%s', got '%v'", row, node, got[row])
+		}
+	}
+}
+
+func TestRenderRowIndexFootnotes(t *testing.T) {
+	a := NewNode("root")
+	c := a.NewChild("child1")
+	g := c.NewChild("grandchild1")
+	gg := g.NewChild("greatgrandchild1")
+	d := a.NewChild("child2")
+	got := a.RenderRowIndex(&DrawInput{MaxWidth: 20})
+	expected := map[int]*Node{0: a, 1: c, 2: d, 4: c, 5: g, 7: g, 8: gg}
+	if len(got) != len(expected) {
+		t.Errorf("expected %d rows, got %d", len(expected), len(got))
+	}
+	for row, node := range expected {
+		if got[row] != node {
+			t.Errorf("row %d, expected '%s', got '%v'", row, node, got[row])
+		}
+	}
+}
+
+func TestEmptyContents(t *testing.T) {
+	newTree := func() *Node {
+		a := NewNode("root")
+		a.NewChild("child1").NewChild("")
+		a.NewChild("").NewChild("grandchild2")
+		a.NewChild("")
+		return a
+	}
+	tests := []struct {
+		di       *DrawInput
+		expected []string
+	}{
+		{
+			&DrawInput{},
+			[]string{
+				"root",
+				"├── child1",
+				"│   └──",
+				"├──",
+				"│   └── grandchild2",
+				"└──",
+			},
+		},
+		{
+			&DrawInput{EmptyPlaceholder: "∅"},
+			[]string{
+				"root",
+				"├── child1",
+				"│   └── ∅",
+				"├── ∅",
+				"│   └── grandchild2",
+				"└── ∅",
+			},
+		},
+		{
+			&DrawInput{PruneEmpty: true},
+			[]string{
+				"root",
+				"├── child1",
+				"└──",
+				"    └── grandchild2",
+			},
+		},
+	}
+	for _, test := range tests {
+		lines := strings.Split(strings.TrimSuffix(newTree().DrawOptions(test.di), "\n"), "\n")
+		if len(lines) != len(test.expected) {
+			t.Errorf("expected %d lines, got %d:\n%s", len(test.expected), len(lines), strings.Join(lines, "\n"))
+			continue
+		}
+		for i, e := range test.expected {
+			if strings.TrimRight(lines[i], " ") != e {
+				t.Errorf("line %d, expected '%s', got '%s'", i, e, lines[i])
+			}
+		}
+	}
+}
+
+func TestToSlice(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	c := a.NewChild("child2")
+	got := a.ToSlice(&DrawInput{Border: true})
+	expected := []RenderedLine{
+		{nil, "┌───────────┐"},
+		{a, "│ root      │"},
+		{b, "│ ├── child1│"},
+		{c, "│ └── child2│"},
+		{nil, "└───────────┘"},
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d lines, got %d", len(expected), len(got))
+	}
+	for i, e := range expected {
+		if got[i].Node != e.Node || got[i].Line != e.Line {
+			t.Errorf("line %d, expected %v '%s', got %v '%s'", i, e.Node, e.Line, got[i].Node, got[i].Line)
+		}
+	}
+}
+
+func TestTrimLongLabel(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("a label that is much too long to fit")
+	tests := []struct {
+		di       *DrawInput
+		expected []string
+	}{
+		{
+			&DrawInput{MaxWidth: 20},
+			[]string{
+				"root",
+				"└── a label that ...",
+			},
+		},
+		{
+			&DrawInput{MaxWidth: 20, Ellipsis: "…"},
+			[]string{
+				"root",
+				"└── a label that is…",
+			},
+		},
+		{
+			&DrawInput{MaxWidth: 20, Border: true},
+			[]string{
+				"┌──────────────────┐",
+				"│ root             │",
+				"│ └── a label th...│",
+				"└──────────────────┘",
+			},
+		},
+	}
+	for _, test := range tests {
+		lines := strings.Split(strings.TrimSuffix(a.DrawOptions(test.di), "\n"), "\n")
+		if len(lines) != len(test.expected) {
+			t.Errorf("expected %d lines, got %d:\n%s", len(test.expected), len(lines), strings.Join(lines, "\n"))
+			continue
+		}
+		for i, e := range test.expected {
+			if strings.TrimRight(lines[i], " ") != e {
+				t.Errorf("line %d, expected '%s', got '%s'", i, e, lines[i])
+			}
+		}
+	}
+}
+
+func TestTerminalWidth(t *testing.T) {
+	defer func(size func() (int, int)) { consoleSize = size }(consoleSize)
+	a := NewNode("root")
+	a.NewChild("a-rather-long-name")
+	consoleSize = func() (int, int) { return 0, 0 }
+	expected := []string{
+		"┌───────────────────────┐",
+		"│ root                  │",
+		"│ └── a-rather-long-name│",
+		"└───────────────────────┘",
+	}
+	if got := a.DrawLines(&DrawInput{Border: true}); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected no limit without a terminal, got\n%s", strings.Join(got, "\n"))
+	}
+	consoleSize = func() (int, int) { return 20, 10 }
+	expected = []string{
+		"┌──────────────┐",
+		"│ root         │",
+		"│ └── a-rath...│",
+		"└──────────────┘",
+	}
+	if got := a.DrawLines(&DrawInput{Border: true}); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected the drawing trimmed to the terminal, got\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestDrawIsolated(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1").SetColorRed()
+	b.NewChild("grandchild1")
+	a.NewChild("child2")
+	di := &DrawInput{Border: true, Isolated: true}
+	expected := a.DrawOptions(&DrawInput{Border: true})
+	a.ResetLayout()
+	done := make(chan string)
+	for i := 0; i < 4; i++ {
+		go func() { done <- a.DrawOptions(di) }()
+	}
+	for i := 0; i < 4; i++ {
+		if got := <-done; got != expected {
+			t.Errorf("expected\n%s\ngot\n%s", expected, got)
+		}
+	}
+	if b.label != "" || b.x1 != 0 || len(b.lineage) != 0 {
+		t.Errorf("expected original nodes to be left untouched")
+	}
+	if got := a.RenderRowIndex(di); got[2] != b {
+		t.Errorf("expected rows to map to the original nodes, got %v", got[2])
+	}
+}
+
+func TestDrawIsolatedSubtree(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1")
+	b := a.NewChild("child2")
+	b.NewChild("grandchild1")
+	di := &DrawInput{ShowIndex: true, ShowParentStub: true}
+	expected := b.DrawLines(di)
+	di.Isolated = true
+	got := b.DrawLines(di)
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+	if !strings.HasPrefix(got[1], "[1] child2") {
+		t.Errorf("expected the root to keep its index, got '%s'", got[1])
+	}
+}
+
+func TestDrawCopiesDuplicateIDs(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	b.NewChild("grandchild1")
+	c := a.AddChild(b.CloneKeepIDs())
+	c.SetContents("copy")
+	for name, di := range map[string]*DrawInput{
+		"isolated":   {Isolated: true, ShowIndex: true},
+		"chained":    {ChainInline: true, ShowIndex: true},
+		"summarized": {SummarizeDeeperThan: 1, ShowIndex: true},
+		"split":      {MaxWidth: 12, ShowIndex: true},
+	} {
+		lines := a.ToSlice(di)
+		found := false
+		for _, line := range lines {
+			if strings.Contains(line.Line, "copy") {
+				found = true
+				if line.Node != c {
+					t.Errorf("%s, expected the row of the copy to map to it, got %v", name, line.Node)
+				}
+			}
+			if strings.HasSuffix(strings.TrimRight(line.Line, " "), "] child1") && line.Node != b {
+				t.Errorf("%s, expected the row of child1 to map to it, got %v", name, line.Node)
+			}
+		}
+		if !found {
+			t.Errorf("%s, expected a row for the copy, got %v", name, lines)
+		}
+	}
+}
+
+func BenchmarkDraw(b *testing.B) {
+	a := benchTree()
+	for i := 0; i < b.N; i++ {
+		a.DrawOptions(&DrawInput{})
+	}
+}
+
+func BenchmarkDrawIsolated(b *testing.B) {
+	a := benchTree()
+	for i := 0; i < b.N; i++ {
+		a.DrawOptions(&DrawInput{Isolated: true})
+	}
+}
+
+// benchTree returns a tree of 156 nodes, 5 wide and 3 deep
+func benchTree() *Node {
+	a := NewNode("root")
+	for i := 0; i < 5; i++ {
+		c := a.NewChild(fmt.Sprintf("child%d", i))
+		for j := 0; j < 5; j++ {
+			g := c.NewChild(fmt.Sprintf("grandchild%d", j))
+			for k := 0; k < 5; k++ {
+				g.NewChild(fmt.Sprintf("greatgrandchild%d", k))
+			}
+		}
+	}
+	return a
+}
+
+func TestRootLabel(t *testing.T) {
+	a := NewNode("/home/user/project").SetStatus(Success)
+	a.NewChild("main.go")
+	got := strings.Split(a.DrawOptions(&DrawInput{RootLabel: ".", ShowStatus: true}), "\n")
+	expected := []string{
+		"✓ .",
+		"└── main.go",
+	}
+	for i, e := range expected {
+		if strings.TrimRight(got[i], " ") != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+		}
+	}
+	if a.String() != "/home/user/project" {
+		t.Errorf("expected contents to be left alone, got '%s'", a.String())
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1").NewChild("grandchild1")
+	snap := a.Snapshot()
+	expected := a.Draw()
+	done := make(chan string)
+	go func() { done <- snap.Draw() }()
+	for i := 0; i < 10; i++ {
+		a.NewChild(fmt.Sprintf("added%d", i))
+	}
+	a.GetChild(0).SetContents("renamed")
+	if got := <-done; got != expected {
+		t.Errorf("expected snapshot to draw as it was taken, got\n%s", got)
+	}
+	if snap.GetID() != a.GetID() || snap.NumChildren() != 1 {
+		t.Errorf("expected snapshot to keep ids and structure")
+	}
+}
+
+func TestAdd(t *testing.T) {
+	b := NewNode("child2")
+	b.NewChild("grandchild1")
+	a := NewNode("root").Add("child1", b, "child3")
+	expected := "root(child1, child2(grandchild1), child3)"
+	if got := a.Summary(); got != expected {
+		t.Errorf("expected '%s', got '%s'", expected, got)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic for unsupported type")
+		}
+		if a.NumChildren() != 3 {
+			t.Errorf("expected nothing to be added before panicking")
+		}
+	}()
+	a.Add("child4", 5)
+}
+
+func TestViewport(t *testing.T) {
+	newTree := func() *Node {
+		a := NewNode("root")
+		a.NewChild("child1").NewChild("a much longer grandchild")
+		a.NewChild("child2")
+		a.NewChild("child3")
+		return a
+	}
+	tests := []struct {
+		di       *DrawInput
+		expected []string
+	}{
+		{
+			&DrawInput{ViewportTop: 2, ViewportHeight: 2, Border: true},
+			[]string{
+				"┌─────────────────────────────────┐",
+				"│ │   └── a much longer grandchild│",
+				"│ ├── child2                      │",
+				"└─────────────────────────────────┘",
+			},
+		},
+		{
+			&DrawInput{ViewportTop: 3, ViewportHeight: 5},
+			[]string{
+				"├── child2",
+				"└── child3",
+			},
+		},
+		{
+			&DrawInput{ViewportTop: 9, ViewportHeight: 5},
+			[]string{},
+		},
+	}
+	for _, test := range tests {
+		got := strings.Split(newTree().DrawOptions(test.di), "\n")
+		got = got[:len(got)-1]
+		if len(got) != len(test.expected) {
+			t.Errorf("expected %d lines, got %d:\n%s", len(test.expected), len(got), strings.Join(got, "\n"))
+			continue
+		}
+		for i, e := range test.expected {
+			if strings.TrimRight(got[i], " ") != strings.TrimRight(e, " ") {
+				t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+			}
+		}
+	}
+}
+
+func TestZebra(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+	a := NewNode("root")
+	a.NewChild("child1").SetColor(color.FgRed)
+	a.NewChild("child2")
+	lines := strings.Split(a.DrawOptions(&DrawInput{Zebra: true, Border: true}), "\n")
+	bg := color.New(color.BgHiBlack)
+	expected := []string{
+		"┌───────────┐",
+		"│ root      │",
+		bg.Sprint("│ ├── ") + color.New(color.BgHiBlack, color.FgRed).Sprint("child1") + bg.Sprint("│"),
+		"│ └── child2│",
+		"└───────────┘",
+	}
+	for i, e := range expected {
+		if lines[i] != e {
+			t.Errorf("line %d, expected %q, got %q", i, e, lines[i])
+		}
+	}
+}
+
+func TestDrawLines(t *testing.T) {
+	newTree := func() *Node {
+		a := NewNode("root")
+		a.NewChild("child1").NewChild("grandchild1")
+		a.NewChild("child2")
+		return a
+	}
+	for _, di := range []*DrawInput{
+		{},
+		{Border: true},
+		{Debug: true, Legend: map[string]color.Attribute{"red": color.FgRed}},
+	} {
+		expected := strings.Split(strings.TrimSuffix(newTree().DrawOptions(di), "\n"), "\n")
+		got := newTree().DrawLines(di)
+		if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+			t.Errorf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
+
+func TestNilNode(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1")
+	missing := a.GetChild(5)
+	if missing.GetChild(0) != nil || a.GetChild(5).GetChild(0).GetChild(1) != nil {
+		t.Errorf("expected chained GetChild on a missing child to yield nil")
+	}
+	if missing.NewChild("orphan") != nil || missing.AddChild(NewNode("orphan")) != nil {
+		t.Errorf("expected adding to a nil node to return nil")
+	}
+	if a.AddChild(nil) != nil || a.NumChildren() != 1 {
+		t.Errorf("expected adding a nil child to do nothing")
+	}
+	if missing.NumChildren() != 0 || missing.GetDepth() != 0 || missing.Height() != 0 ||
+		missing.MaxDepth() != 0 || missing.SiblingCount() != 0 || missing.IsLastSibling() ||
+		missing.GetID() != "" || missing.GetStatus() != NoStatus || missing.HasTag("x") ||
+		len(missing.GetGeneration(1)) != 0 || len(missing.GetAllDescendents()) != 0 ||
+		missing.GetData() != nil || missing.Left() != nil || missing.Right() != nil {
+		t.Errorf("expected zero values from a nil node")
+	}
+	missing.Walk(func(node *Node) bool {
+		t.Errorf("expected Walk on a nil node not to call fn")
+		return true
+	})
+	missing.WalkSafe(func(node *Node) WalkAction {
+		t.Errorf("expected WalkSafe on a nil node not to call fn")
+		return Continue
+	})
+	if missing.ColorWhere(func(*Node) bool { return true }, color.FgRed) != 0 {
+		t.Errorf("expected ColorWhere on a nil node to color nothing")
+	}
+}
+
+func TestRulerInterval(t *testing.T) {
+	tests := []struct {
+		width    int
+		interval int
+		expected string
+	}{
+		{12, 0, "\n|....|....|..\n0    5    10 \n"},
+		{12, 2, "\n|.|.|.|.|.|.|\n0 2 4 6 8 10 \n"},
+		{24, 10, "\n|.........|.........|....\n0         10        20   \n"},
+		{104, 2, "\n" + strings.Repeat("|.", 52) + "|\n" +
+			"0 2 4 6 8 10  14  18  22  26  30  34  38  42  46  50  54  58  62  66  70  74  78  82  86  90  94  98  102\n"},
+	}
+	for _, test := range tests {
+		if got := drawRuler(test.width, test.interval); got != test.expected {
+			t.Errorf("width %d interval %d, expected %q, got %q", test.width, test.interval, test.expected, got)
+		}
+	}
+}
+
+func TestInverted(t *testing.T) {
+	newTree := func() *Node {
+		a := NewNode("root")
+		b := a.NewChild("child1")
+		b.NewChild("grandchild1")
+		b.NewChild("└ quoted")
+		a.NewChild("child2")
+		return a
+	}
+	tests := []struct {
+		di       *DrawInput
+		expected []string
+	}{
+		{
+			&DrawInput{Inverted: true},
+			[]string{
+				"┌── child2",
+				"│   ┌── └ quoted",
+				"│   ├── grandchild1",
+				"├── child1",
+				"root",
+			},
+		},
+		{
+			&DrawInput{Inverted: true, Stacked: true},
+			[]string{
+				"    child2",
+				"┌───┘",
+				"│       └ quoted",
+				"│   ┌───┘",
+				"│   │   grandchild1",
+				"│   ├───┘",
+				"│   child1",
+				"├───┘",
+				"root",
+			},
+		},
+	}
+	for _, test := range tests {
+		got := newTree().DrawLines(test.di)
+		if len(got) != len(test.expected) {
+			t.Errorf("expected %d lines, got %d:\n%s", len(test.expected), len(got), strings.Join(got, "\n"))
+			continue
+		}
+		for i, e := range test.expected {
+			if strings.TrimRight(got[i], " ") != e {
+				t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+			}
+		}
+	}
+}
+
+func TestSetContentsRaw(t *testing.T) {
+	raw := "\x1b[1;35mbold\x1b[0m \x1b[4mlink\x1b[0m"
+	a := NewNode("root")
+	a.NewChild("child1").SetStatus(Success)
+	a.GetChild(0).SetContentsRaw(raw, 9)
+	a.NewChild("child2")
+	lines := a.DrawLines(&DrawInput{Border: true, ShowStatus: true})
+	expected := []string{
+		"┌────────────────┐",
+		"│ root           │",
+		"│ ├── ✓ " + raw + "│",
+		"│ └── child2     │",
+		"└────────────────┘",
+	}
+	for i, e := range expected {
+		if lines[i] != e {
+			t.Errorf("line %d, expected %q, got %q", i, e, lines[i])
+		}
+	}
+	if got := a.GetChild(0).String(); got != "bold link" {
+		t.Errorf("expected plain contents, got '%s'", got)
+	}
+	b := NewNode("root")
+	b.NewChild("child1").SetContentsRaw(raw, 9)
+	b.GetChild(0).SetContents("plain")
+	if lines := b.DrawLines(&DrawInput{}); strings.TrimRight(lines[1], " ") != "└── plain" {
+		t.Errorf("expected SetContents to drop the raw contents, got %q", lines[1])
+	}
+}
+
+func TestSetContentsRawTrimmed(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1").SetContentsRaw("\x1b[31ma rather long raw label\x1b[0m", 23)
+	lines := a.DrawLines(&DrawInput{MaxWidth: 16})
+	if got := strings.TrimRight(lines[1], " "); got != "└── a rather ..." {
+		t.Errorf("expected trimmed plain contents, got %q", got)
+	}
+}
+
+func TestReparentGeneration(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	b.NewChild("grandchild1").NewChild("greatgrandchild1")
+	b.NewChild("grandchild2")
+	c := a.NewChild("child2")
+	c.NewChild("grandchild3")
+	if err := a.ReparentGeneration(2, a); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := "root(child1, child2, grandchild1(greatgrandchild1), grandchild2, grandchild3)"
+	if got := a.Summary(); got != expected {
+		t.Errorf("expected '%s', got '%s'", expected, got)
+	}
+	if got := a.GetChild(2).GetChild(0).GetDepth(); got != 2 {
+		t.Errorf("expected depths to be updated, got %d", got)
+	}
+	if err := a.ReparentGeneration(1, a.GetChild(2).GetChild(0)); err == nil {
+		t.Errorf("expected error reparenting a generation beneath itself")
+	}
+	if got := a.Summary(); got != expected {
+		t.Errorf("expected nothing to move after an error, got '%s'", got)
+	}
+}
+
+// asymmetricTree has an early child with a deep subtree
+// followed by a leaf child and a shallower last child
+func asymmetricTree() *Node {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	g := b.NewChild("grandchild1")
+	g.NewChild("greatgrandchild1").NewChild("ggreatgrandchild1")
+	g.NewChild("greatgrandchild2")
+	b.NewChild("grandchild2").NewChild("greatgrandchild3")
+	a.NewChild("child2")
+	a.NewChild("child3").NewChild("grandchild3").NewChild("greatgrandchild4")
+	return a
+}
+
+func TestDrawAsymmetric(t *testing.T) {
+	tests := map[string]*DrawInput{
+		"./testdata/TestDrawAsymmetric.txt":        {},
+		"./testdata/TestDrawAsymmetricStacked.txt": {Stacked: true},
+		"./testdata/TestDrawAsymmetricBorder.txt":  {Border: true},
+	}
+	for testfile, di := range tests {
+		dat, err := os.ReadFile(testfile)
+		if err != nil {
+			t.Fatalf("error pulling expected from file '%s', error '%s'\n", testfile, err.Error())
+		}
+		expected := strings.Split(strings.TrimSuffix(string(dat), "\n"), "\n")
+		got := asymmetricTree().DrawLines(di)
+		if len(got) != len(expected) {
+			t.Errorf("%s: expected %d lines, got %d:\n%s", testfile, len(expected), len(got), strings.Join(got, "\n"))
+			continue
+		}
+		for i, e := range expected {
+			if strings.TrimRight(got[i], " ") != e {
+				t.Errorf("%s: line %d, expected '%s', got '%s'", testfile, i, e, got[i])
+			}
+		}
+	}
+}
+
+func TestFixedWidth(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+	newTree := func() *Node {
+		a := NewNode("root")
+		a.NewChild("a label that is too long for the panel").SetColorRed()
+		a.NewChild("child2")
+		return a
+	}
+	for _, di := range []*DrawInput{
+		{FixedWidth: 24},
+		{FixedWidth: 24, Border: true},
+		{FixedWidth: 60, Border: true, MaxWidth: 20},
+	} {
+		lines := newTree().DrawLines(di)
+		for i, line := range lines {
+			if VisibleWidth(line) != di.FixedWidth {
+				t.Errorf("line %d has width %d, expected %d: %q", i, VisibleWidth(line), di.FixedWidth, line)
+			}
+		}
+	}
+	lines := newTree().DrawLines(&DrawInput{FixedWidth: 24, Border: true})
+	expected := "│ └── child2           │"
+	if lines[3] != expected {
+		t.Errorf("expected '%s', got '%s'", expected, lines[3])
+	}
+	for _, fixed := range []int{1, 2} {
+		lines = newTree().DrawLines(&DrawInput{FixedWidth: fixed, Border: true})
+		if lines[0] != "┌┐" || lines[len(lines)-1] != "└┘" {
+			t.Errorf("FixedWidth %d, expected a border 2 columns wide, got %q", fixed, lines)
+		}
+	}
+}
+
+func TestMaxWidthBorder(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1")
+	a.NewChild("child2").NewChild("grandchild1")
+	for _, max := range []int{1, 2} {
+		lines := a.DrawLines(&DrawInput{MaxWidth: max, Border: true})
+		if lines[0] != "┌┐" || lines[len(lines)-1] != "└┘" {
+			t.Errorf("MaxWidth %d, expected a border 2 columns wide, got %q", max, lines)
+		}
+		a.DrawOptions(&DrawInput{MaxWidth: max, Border: true, Debug: true})
+	}
+}
+
+func TestShowIndex(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1").NewChild("grandchild1")
+	a.NewChild("child2").SetStatus(Failure)
+	lines := a.DrawLines(&DrawInput{ShowIndex: true, ShowStatus: true, Border: true})
+	expected := []string{
+		"┌────────────────────────┐",
+		"│ root                   │",
+		"│ ├── [0] child1         │",
+		"│ │   └── [0] grandchild1│",
+		"│ └── [1] ✗ child2       │",
+		"└────────────────────────┘",
+	}
+	for i, e := range expected {
+		if lines[i] != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, lines[i])
+		}
+	}
+}
+
+func TestRenderedWidth(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+	newTree := func() *Node {
+		a := NewNode("root")
+		a.NewChild("child1").SetColorRed().NewChild("grandchild1")
+		a.NewChild("child2").SetStatus(Success)
+		return a
+	}
+	tests := []struct {
+		di       *DrawInput
+		expected int
+	}{
+		{&DrawInput{}, 19},
+		{&DrawInput{Border: true}, 22},
+		{&DrawInput{ShowIndex: true}, 23},
+		{&DrawInput{FixedWidth: 40}, 40},
+	}
+	for _, test := range tests {
+		if got := newTree().RenderedWidth(test.di); got != test.expected {
+			t.Errorf("%+v, expected %d, got %d", test.di, test.expected, got)
+		}
+	}
+}
+
+func TestSetPadding(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	b.NewChild("grandchild1")
+	b.NewChild("grandchild2").NewChild("greatgrandchild1")
+	a.NewChild("child2").NewChild("grandchild3")
+	if err := b.SetPaddingAll("      "); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetPadding(""); err == nil {
+		t.Errorf("expected error setting empty padding")
+	}
+	expected := []string{
+		"root",
+		"├───── child1",
+		"│      ├───── grandchild1",
+		"│      └───── grandchild2",
+		"│             └───── greatgrandchild1",
+		"└── child2",
+		"    └── grandchild3",
+	}
+	for _, got := range []string{a.Draw(), a.DrawOptions(&DrawInput{})} {
+		lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+		if len(lines) != len(expected) {
+			t.Fatalf("expected %d lines, got\n%s", len(expected), got)
+		}
+		for i, e := range expected {
+			if strings.TrimRight(lines[i], " ") != e {
+				t.Errorf("line %d, expected '%s', got '%s'", i, e, lines[i])
+			}
+		}
+	}
+	// DrawInput.Padding still overrides every node
+	got := a.DrawLines(&DrawInput{Padding: "   "})
+	if strings.TrimRight(got[1], " ") != "├── child1" {
+		t.Errorf("expected DrawInput.Padding to override node padding, got '%s'", got[1])
+	}
+}
+
+func TestEdgeLabels(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("yes").SetEdgeLabel("0.7")
+	b.NewChild("grandchild1").SetEdgeLabel("a")
+	b.NewChild("grandchild2")
+	a.NewChild("no").SetEdgeLabel("0.3")
+	tests := []struct {
+		di       *DrawInput
+		expected []string
+	}{
+		{&DrawInput{}, []string{
+			"root",
+			"├── yes",
+			"│   ├── grandchild1",
+			"│   └── grandchild2",
+			"└── no",
+		}},
+		{&DrawInput{ShowEdgeLabels: true}, []string{
+			"root",
+			"├──[0.7]── yes",
+			"│          ├──[a]── grandchild1",
+			"│          └── grandchild2",
+			"└──[0.3]── no",
+		}},
+		{&DrawInput{ShowEdgeLabels: true, Stacked: true}, []string{
+			"root",
+			"├──[0.7]───┐",
+			"│          yes",
+			"│          ├──[a]───┐",
+			"│          │        grandchild1",
+			"│          └───┐",
+			"│              grandchild2",
+			"└──[0.3]───┐",
+			"           no",
+		}},
+	}
+	for _, tt := range tests {
+		got := a.DrawLines(tt.di)
+		if len(got) != len(tt.expected) {
+			t.Fatalf("expected %d lines, got\n%s", len(tt.expected), strings.Join(got, "\n"))
+		}
+		for i, e := range tt.expected {
+			if strings.TrimRight(got[i], " ") != e {
+				t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+			}
+		}
+	}
+	if b.GetEdgeLabel() != "0.7" {
+		t.Errorf("expected edge label '0.7', got '%s'", b.GetEdgeLabel())
+	}
+}
+
+func TestDrawBorderTwice(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1").NewChild("grandchild1")
+	a.NewChild("child2")
+	for _, di := range []*DrawInput{
+		{Border: true},
+		{Border: true, Stacked: true},
+		{Border: true, MinDepth: 1},
+	} {
+		first := a.DrawOptions(di)
+		second := a.DrawOptions(di)
+		if first != second {
+			t.Errorf("expected identical bordered draws, got\n%s\nthen\n%s", first, second)
+		}
+		plain := *di
+		plain.Border = false
+		if line := a.DrawLines(&plain)[1]; !strings.Contains(first, "│ "+strings.TrimRight(line, " ")) {
+			t.Errorf("expected bordered draw to wrap the plain draw, got\n%s", first)
+		}
+	}
+}
+
+func TestDimNonMatching(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+	a := NewNode("root")
+	a.NewChild("child1").NewChild("leaf1")
+	a.NewChild("child2").SetColor(color.FgBlue)
+	lines := a.DrawLines(&DrawInput{DimNonMatching: "child1"})
+	expected := []string{
+		color.New(color.Faint).Sprint("root"),
+		"├── child1",
+		"│   └── " + color.New(color.Faint).Sprint("leaf1"),
+		"└── " + color.New(color.FgBlue, color.Faint).Sprint("child2"),
+	}
+	for i, e := range expected {
+		if strings.TrimRight(lines[i], " ") != e {
+			t.Errorf("line %d, expected %q, got %q", i, e, lines[i])
+		}
+	}
+	if got := a.GetChild(1).DrawLines(&DrawInput{}); strings.TrimRight(got[0], " ") != color.New(color.FgBlue).Sprint("child2") {
+		t.Errorf("expected colors to be left as is, got %q", got[0])
+	}
+}
+
+func TestLogPrefix(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	g := b.NewChild("grandchild1")
+	b.NewChild("grandchild2")
+	c := a.NewChild("child2")
+	tests := map[*Node]string{
+		a: "",
+		b: "├── ",
+		g: "│   ├── ",
+		c: "└── ",
+	}
+	for node, expected := range tests {
+		if got := node.LogPrefix(); got != expected {
+			t.Errorf("%s: expected prefix '%s', got '%s'", node, expected, got)
+		}
+	}
+	for _, line := range a.DrawLines(&DrawInput{}) {
+		if strings.HasSuffix(strings.TrimRight(line, " "), "grandchild1") && line[:len(g.LogPrefix())] != g.LogPrefix() {
+			t.Errorf("expected prefix to match the drawing, got '%s'", line)
+		}
+	}
+}
+
+func TestDecoratorColumns(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1").SetDecorator("", "x")
+	b.NewChild("grandchild1")
+	c := a.NewChild("child2").SetDecorator("🌳", "🌲")
+	c.NewChild("grandchild2").NewChild("greatgrandchild1")
+	c.NewChild("grandchild3")
+	if err := c.SetPadding(" "); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"root",
+		"├── child1",
+		"│   └── grandchild1",
+		"🌲 child2",
+		"   ├── grandchild2",
+		"   │   └── greatgrandchild1",
+		"   └── grandchild3",
+	}
+	got := a.DrawLines(&DrawInput{})
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d lines, got\n%s", len(expected), strings.Join(got, "\n"))
+	}
+	for i, e := range expected {
+		if strings.TrimRight(got[i], " ") != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+		}
+		if w := VisibleWidth(got[i]); w != VisibleWidth(got[0]) {
+			t.Errorf("line %d, expected width %d, got %d", i, VisibleWidth(got[0]), w)
+		}
+	}
+}
+
+func TestRedactLabels(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("secret").SetEdgeLabel("0.5")
+	b.NewChild("名前")
+	b.SetMeta("42")
+	a.NewChild("x")
+	expected := []string{
+		"••••",
+		"├──[•••]── ••••••   ••",
+		"│          └── ••••",
+		"└── •",
+	}
+	di := &DrawInput{RedactLabels: true, ShowEdgeLabels: true, MetaColumn: true}
+	got := a.DrawLines(di)
+	for i, e := range expected {
+		if strings.TrimRight(got[i], " ") != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+		}
+	}
+	di.RedactLabels = false
+	plain := a.DrawLines(di)
+	for i := range plain {
+		if VisibleWidth(plain[i]) != VisibleWidth(got[i]) {
+			t.Errorf("line %d, expected redacting to keep the width", i)
+		}
+	}
+	if got := a.DrawLines(&DrawInput{RedactLabels: true, RedactMask: '#'}); strings.TrimRight(got[0], " ") != "####" {
+		t.Errorf("expected custom mask, got '%s'", got[0])
+	}
+}
+
+func TestSortStable(t *testing.T) {
+	data := map[string][]string{"b": {"z", "y"}, "a": {"x"}, "c": nil}
+	var drawings []string
+	for i := 0; i < 5; i++ {
+		a := NewNode("root")
+		for parent, children := range data {
+			p := a.NewChild(parent)
+			for _, child := range children {
+				p.NewChild(child)
+			}
+		}
+		// same contents, told apart by their children
+		a.NewChild("a").NewChild("w")
+		a.SortStable()
+		drawings = append(drawings, a.Draw())
+		if i == 0 {
+			expected := "root(a(w), a(x), b(y, z), c)"
+			if got := a.Summary(); got != expected {
+				t.Errorf("expected '%s', got '%s'", expected, got)
+			}
+		}
+	}
+	for _, d := range drawings[1:] {
+		if d != drawings[0] {
+			t.Errorf("expected identical drawings, got\n%s\nand\n%s", drawings[0], d)
+		}
+	}
+}
+
+func TestColumnBudget(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	b.NewChild("grandchild1").NewChild("greatgrandchild1")
+	c := a.NewChild("child2")
+	c.NewChild("grandchild2")
+	if err := c.SetPadding("      "); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		di       *DrawInput
+		expected map[int]int
+	}{
+		{&DrawInput{}, map[int]int{0: 0, 1: 4, 2: 8, 3: 12}},
+		{&DrawInput{Border: true}, map[int]int{0: 2, 1: 6, 2: 10, 3: 14}},
+		{&DrawInput{MinDepth: 1, MaxDepth: 2}, map[int]int{1: 0, 2: 4}},
+	}
+	for _, tt := range tests {
+		got := a.ColumnBudget(tt.di)
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("expected %v, got %v", tt.expected, got)
+		}
+	}
+	// the budget matches where the labels are drawn
+	lines := a.DrawLines(&DrawInput{})
+	if col := VisibleWidth(lines[2][:strings.Index(lines[2], "grandchild1")]); col != 8 {
+		t.Errorf("expected grandchild1 at column 8, got %d", col)
+	}
+}
+
+func TestSetColorRange(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+	a := NewNode("root")
+	a.NewChild("status: OK").SetColorRange(8, 10, color.FgGreen)
+	a.NewChild("a: ok b: bad").SetColorRange(3, 5, color.FgGreen).SetColorRange(9, 20, color.FgRed)
+	a.NewChild("empty").SetColorRange(3, 3, color.FgRed)
+	lines := a.DrawLines(&DrawInput{ShowIndex: true})
+	expected := []string{
+		"root",
+		"├── [0] status: " + color.New(color.FgGreen).Sprint("OK"),
+		"├── [1] a: " + color.New(color.FgGreen).Sprint("ok") + " b: " + color.New(color.FgRed).Sprint("bad"),
+		"└── [2] empty",
+	}
+	for i, e := range expected {
+		if strings.TrimRight(lines[i], " ") != e {
+			t.Errorf("line %d, expected %q, got %q", i, e, lines[i])
+		}
+	}
+	color.NoColor = true
+	plain := a.DrawLines(&DrawInput{ShowIndex: true})
+	for i := range plain {
+		if VisibleWidth(lines[i]) != VisibleWidth(plain[i]) {
+			t.Errorf("line %d, expected colored width %d, got %d", i, VisibleWidth(plain[i]), VisibleWidth(lines[i]))
+		}
+	}
+}
+
+func TestCoalesceRepeats(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("retry")
+	a.NewChild("retry")
+	a.NewChild("retry")
+	a.NewChild("ok")
+	a.NewChild("retry")
+	b := a.NewChild("job")
+	b.NewChild("step")
+	b.NewChild("step")
+	c := a.NewChild("job")
+	c.NewChild("step")
+	c.NewChild("step")
+	a.NewChild("job").NewChild("other")
+	a.CoalesceRepeats()
+	expected := `root(retry \(×3\), ok, retry, job \(×2\)(step \(×2\)), job(other))`
+	if got := a.Summary(); got != expected {
+		t.Errorf("expected '%s', got '%s'", expected, got)
+	}
+	if a.GetChild(3) != b {
+		t.Errorf("expected the first of a run to be kept")
+	}
+}
+
+func TestDrawInto(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1")
+	di := &DrawInput{Border: true, Debug: true}
+	var b strings.Builder
+	b.WriteString("report:\n")
+	a.DrawInto(&b, di)
+	b.WriteString("end\n")
+	if expected := "report:\n" + a.DrawOptions(di) + "end\n"; b.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, b.String())
+	}
+}
+
+func TestPrefixSuffixFunc(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("src")
+	b.NewChild("main.go").SetMeta("1k")
+	a.NewChild("README")
+	di := &DrawInput{
+		MetaColumn: true,
+		PrefixFunc: func(n *Node) string {
+			if n.NumChildren() > 0 {
+				return "📁 "
+			}
+			return "📄 "
+		},
+		SuffixFunc: func(n *Node) string {
+			if n.NumChildren() > 0 {
+				return "/"
+			}
+			return ""
+		},
+	}
+	expected := []string{
+		"📁 root/",
+		"├── 📁 src/",
+		"│   └── 📄 main.go 1k",
+		"└── 📄 README",
+	}
+	got := a.DrawLines(di)
+	for i, e := range expected {
+		if strings.TrimRight(got[i], " ") != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+		}
+		if VisibleWidth(got[i]) != VisibleWidth(got[0]) {
+			t.Errorf("line %d, expected width %d, got %d", i, VisibleWidth(got[0]), VisibleWidth(got[i]))
+		}
+	}
+}
+
+func TestLiteralNodes(t *testing.T) {
+	a := &Node{}
+	a.SetContents("root")
+	b := a.AddChild(&Node{})
+	b.SetContents("child1")
+	b.NewChild("grandchild1")
+	c := &Node{}
+	c.SetContents("child2")
+	a.AddChild(c)
+	seen := make(map[string]bool)
+	a.Walk(func(n *Node) bool {
+		if _, err := uuid.Parse(n.GetID()); err != nil {
+			t.Errorf("%s: expected a valid id, got '%s'", n, n.GetID())
+		}
+		if seen[n.GetID()] {
+			t.Errorf("%s: expected a unique id, got '%s'", n, n.GetID())
+		}
+		seen[n.GetID()] = true
+		return true
+	})
+	expected := NewNode("root")
+	expected.NewChild("child1").NewChild("grandchild1")
+	expected.NewChild("child2")
+	if got := a.Draw(); got != expected.Draw() {
+		t.Errorf("expected literal nodes to draw like NewNode ones, got\n%s", got)
+	}
+	if _, err := uuid.Parse((&Node{}).GetID()); err != nil {
+		t.Errorf("expected a lone literal node to get an id, got %v", err)
+	}
+}
+
+func TestReducer(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("a")
+	b.NewChild("2")
+	b.NewChild("3")
+	a.NewChild("5")
+	sum := func(node *Node, childResults []string) string {
+		if len(childResults) == 0 {
+			return node.String()
+		}
+		total := 0
+		for _, r := range childResults {
+			var v int
+			fmt.Sscan(r, &v)
+			total += v
+		}
+		return fmt.Sprint(total)
+	}
+	expected := []string{
+		"root = 10",
+		"├── a = 5",
+		"│   ├── 2 = 2",
+		"│   └── 3 = 3",
+		"└── 5 = 5",
+	}
+	got := a.DrawLines(&DrawInput{Reducer: sum})
+	for i, e := range expected {
+		if strings.TrimRight(got[i], " ") != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+		}
+	}
+	got = a.DrawLines(&DrawInput{Reducer: sum, MaxDepth: 1})
+	if strings.TrimRight(got[1], " ") != "├── a = 5" {
+		t.Errorf("expected hidden children to be reduced, got '%s'", got[1])
+	}
+	b.GetChild(0).NewChild("2")
+	expected = []string{
+		"root = 10",
+		"├── a [1] = 5",
+		"└── 5 = 5",
+		"",
+		"[1] a = 5",
+		"├── 2 [2] = 2",
+		"└── 3 = 3",
+		"",
+		"[2] 2 = 2",
+		"└── 2 = 2",
+	}
+	got = a.DrawLines(&DrawInput{Reducer: sum, MaxWidth: 16})
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d lines, got %q", len(expected), got)
+	}
+	for i, e := range expected {
+		if strings.TrimRight(got[i], " ") != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+		}
+	}
+}
+
+func TestReducerDuplicateIDs(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	b.NewChild("x")
+	c := a.AddChild(b.CloneKeepIDs())
+	c.NewChild("y")
+	count := func(node *Node, childResults []string) string {
+		total := 1
+		for _, r := range childResults {
+			var v int
+			fmt.Sscan(r, &v)
+			total += v
+		}
+		return fmt.Sprint(total)
+	}
+	for _, di := range []*DrawInput{{Reducer: count}, {Reducer: count, Isolated: true}} {
+		got := a.DrawLines(di)
+		if line := strings.TrimRight(got[1], " "); line != "├── child1 = 2" {
+			t.Errorf("expected child1 to show its own result, got '%s'", line)
+		}
+		if line := strings.TrimRight(got[3], " "); line != "└── child1 = 3" {
+			t.Errorf("expected the copy to show its own result, got '%s'", line)
+		}
+	}
+}
+
+func TestGetChildIndex(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("same")
+	b := a.NewChild("same")
+	if i, ok := a.GetChildIndex(b); !ok || i != 1 {
+		t.Errorf("expected index 1, got %d, %v", i, ok)
+	}
+	if i, ok := a.GetChildIndex(NewNode("same")); ok || i != -1 {
+		t.Errorf("expected a stranger not to be found, got %d, %v", i, ok)
+	}
+	var nilNode *Node
+	if _, ok := nilNode.GetChildIndex(b); ok {
+		t.Errorf("expected nothing found on a nil node")
+	}
+}
+
+func TestParentBranchMark(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	b.NewChild("grandchild1")
+	g := b.NewChild("grandchild2")
+	g.NewChild("greatgrandchild1")
+	a.NewChild("child2")
+	tests := []struct {
+		di       *DrawInput
+		expected []string
+	}{
+		{&DrawInput{ParentBranchMark: true}, []string{
+			"root",
+			"├─┬ child1",
+			"│ ├── grandchild1",
+			"│ └─┬ grandchild2",
+			"│   └── greatgrandchild1",
+			"└── child2",
+		}},
+		{&DrawInput{ParentBranchMark: true, MaxDepth: 2}, []string{
+			"root",
+			"├─┬ child1",
+			"│ ├── grandchild1",
+			"│ └── grandchild2",
+			"└── child2",
+		}},
+	}
+	for _, tt := range tests {
+		got := a.DrawLines(tt.di)
+		if len(got) != len(tt.expected) {
+			t.Fatalf("expected %d lines, got\n%s", len(tt.expected), strings.Join(got, "\n"))
+		}
+		for i, e := range tt.expected {
+			if strings.TrimRight(got[i], " ") != e {
+				t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+			}
+		}
+	}
+}
+
+func TestTrimContents(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild(" child1\t").NewChild("\tgrandchild1 \n")
+	a.NewChild("child2")
+	expected := NewNode("root")
+	expected.NewChild("child1").NewChild("grandchild1")
+	expected.NewChild("child2")
+	a.GetChild(0).TrimContents()
+	if got := a.GetChild(0).String(); got != "child1" {
+		t.Errorf("expected 'child1', got '%s'", got)
+	}
+	if got := a.GetChild(0).GetChild(0).String(); got != "\tgrandchild1 \n" {
+		t.Errorf("expected TrimContents to leave children as is, got %q", got)
+	}
+	a.EnableJournal()
+	a.TrimContentsAll()
+	if got := a.Draw(); got != expected.Draw() {
+		t.Errorf("expected\n%s\ngot\n%s", expected.Draw(), got)
+	}
+	if err := a.Undo(); err != nil || a.GetChild(0).GetChild(0).String() != "\tgrandchild1 \n" {
+		t.Errorf("expected only the changed contents to be journaled")
+	}
+}
+
+func TestSetDrawOrder(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	b.NewChild("grandchild1")
+	a.NewChild("child2")
+	a.NewChild("child3")
+	if err := a.SetDrawOrder([]int{2, 0, 0}); err == nil {
+		t.Errorf("expected error for repeated index")
+	}
+	if err := a.SetDrawOrder([]int{1, 0}); err == nil {
+		t.Errorf("expected error for missing index")
+	}
+	if err := a.SetDrawOrder([]int{2, 0, 1}); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"root",
+		"├── child3",
+		"├── child1",
+		"│   └── grandchild1",
+		"└── child2",
+	}
+	for _, got := range [][]string{a.DrawLines(&DrawInput{}), a.Clone().DrawLines(&DrawInput{})} {
+		for i, e := range expected {
+			if strings.TrimRight(got[i], " ") != e {
+				t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+			}
+		}
+	}
+	if rows := a.RenderRowIndex(&DrawInput{}); rows[1] != a.GetChild(2) || rows[3] != b.GetChild(0) {
+		t.Errorf("expected rows to follow the draw order, got %v", rows)
+	}
+	if a.GetChild(0) != b || a.Summary() != "root(child1(grandchild1), child2, child3)" {
+		t.Errorf("expected the real order to be kept")
+	}
+	a.NewChild("child4")
+	if got := a.DrawLines(&DrawInput{}); strings.TrimRight(got[1], " ") != "├── child1" {
+		t.Errorf("expected adding a child to restore the real order, got '%s'", got[1])
+	}
+}
+
+func TestSetDrawOrderCopies(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	b.NewChild("grandchild-number-one").NewChild("x")
+	b.NewChild("grandchild-number-two").NewChild("y")
+	a.NewChild("child2")
+	b.SetDrawOrder([]int{1, 0})
+	a.SetDrawOrder([]int{1, 0})
+	tests := []struct {
+		di       *DrawInput
+		expected []string
+	}{
+		{&DrawInput{MaxWidth: 28}, []string{
+			"root",
+			"├── child2",
+			"└── child1 [1]",
+			"",
+			"[1] child1",
+			"├── grandchild-number-two",
+			"│   └── y",
+			"└── grandchild-number-one",
+			"    └── x",
+		}},
+		{&DrawInput{ChainInline: true}, []string{
+			"root",
+			"├── child2",
+			"└── child1",
+			"    ├── grandchild-number-two/y",
+			"    └── grandchild-number-one/x",
+		}},
+	}
+	for _, test := range tests {
+		got := a.DrawLines(test.di)
+		if len(got) != len(test.expected) {
+			t.Errorf("expected %d lines, got %q", len(test.expected), got)
+			continue
+		}
+		for i, e := range test.expected {
+			if strings.TrimRight(got[i], " ") != e {
+				t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+			}
+		}
+	}
+}
+
+func TestMinLabelColumn(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1").NewChild("grandchild1")
+	a.NewChild("child2")
+	expected := []string{
+		"    root",
+		"    ├── child1",
+		"    │   └── grandchild1",
+		"    └── child2",
+	}
+	got := a.DrawLines(&DrawInput{MinLabelColumn: 4})
+	for i, e := range expected {
+		if strings.TrimRight(got[i], " ") != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+		}
+	}
+	bordered := a.DrawLines(&DrawInput{MinLabelColumn: 4, Border: true})
+	if strings.TrimRight(bordered[1], " │") != "│   root" {
+		t.Errorf("expected the border to count towards the column, got '%s'", bordered[1])
+	}
+	if got := a.ColumnBudget(&DrawInput{MinLabelColumn: 4, Border: true}); got[0] != 4 || got[2] != 12 {
+		t.Errorf("expected the budget to include the shift, got %v", got)
+	}
+	if got := a.DrawLines(&DrawInput{MinLabelColumn: 2, Border: true}); got[1] != a.DrawLines(&DrawInput{Border: true})[1] {
+		t.Errorf("expected no shift when labels already start past the minimum, got '%s'", got[1])
+	}
+}
+
+func TestAddChildCycle(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	g := b.NewChild("grandchild1")
+	if got := g.AddChild(a); got != nil {
+		t.Errorf("expected nil adding an ancestor, got %v", got)
+	}
+	if err := b.TryAddChild(b); err != ErrCycle {
+		t.Errorf("expected ErrCycle adding a node to itself, got %v", err)
+	}
+	if err := g.MoveTo(b); err != nil {
+		t.Errorf("unexpected error moving beneath a parent: %s", err)
+	}
+	if err := b.MoveTo(g); err != ErrCycle {
+		t.Errorf("expected ErrCycle moving beneath a child, got %v", err)
+	}
+	a.Add(a)
+	if a.parent != nil || b.NumChildren() != 1 || a.NumChildren() != 1 {
+		t.Errorf("expected adding an ancestor as a child to be ignored")
+	}
+	expected := []string{"root", "└── child1", "    └── grandchild1"}
+	got := a.DrawLines(&DrawInput{})
+	for i, e := range expected {
+		if strings.TrimRight(got[i], " ") != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+		}
+	}
+}
+
+func TestFilterGeneration(t *testing.T) {
+	build := func() *Node {
+		a := NewNode("ceo")
+		m1 := a.NewChild("manager1")
+		m1.NewChild("dev1")
+		m1.NewChild("dev2").NewChild("intern")
+		a.NewChild("cto").NewChild("dev3")
+		a.NewChild("manager2").NewChild("dev4")
+		return a
+	}
+	notManager := func(n *Node) bool {
+		return !strings.HasPrefix(n.String(), "manager")
+	}
+	a := build()
+	a.FilterGeneration(1, notManager, true)
+	expected := "ceo(dev1, dev2(intern), cto(dev3), dev4)"
+	if got := a.Summary(); got != expected {
+		t.Errorf("expected '%s', got '%s'", expected, got)
+	}
+	if intern := a.GetChild(1).GetChild(0); intern.GetDepth() != 2 {
+		t.Errorf("expected depth 2 after promotion, got %d", intern.GetDepth())
+	}
+	a = build()
+	a.FilterGeneration(1, notManager, false)
+	if got := a.Summary(); got != "ceo(cto(dev3))" {
+		t.Errorf("expected managers pruned, got '%s'", got)
+	}
+	a = build()
+	a.EnableJournal()
+	a.FilterGeneration(1, notManager, true)
+	for a.Undo() == nil {
+	}
+	if got := a.Summary(); got != build().Summary() {
+		t.Errorf("expected undo to restore the tree, got '%s'", got)
+	}
+}
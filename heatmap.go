@@ -0,0 +1,47 @@
+package gree
+
+import (
+	"math"
+
+	"github.com/fatih/color"
+)
+
+// Heatmap colors this node and each of its descendents by
+// where value(node) falls between the lowest and highest
+// values of the subtree, blending from the low RGB color to
+// the high one. When every value is the same all nodes get
+// the color halfway between. The 24 bit colors replace any
+// colors set before and need a terminal that supports them.
+func (n *Node) Heatmap(value func(*Node) float64, low, high [3]uint8) *Node {
+	values := make(map[*Node]float64)
+	min, max := math.Inf(1), math.Inf(-1)
+	n.Walk(func(node *Node) bool {
+		v := value(node)
+		values[node] = v
+		min = math.Min(min, v)
+		max = math.Max(max, v)
+		return true
+	})
+	for node, v := range values {
+		t := 0.5
+		if max > min {
+			t = (v - min) / (max - min)
+		}
+		node.clearColors()
+		node.SetColors(rgb(blend(low, high, t))...)
+	}
+	return n
+}
+
+// blend returns the color t of the way from low to high
+func blend(low, high [3]uint8, t float64) (c [3]uint8) {
+	for i := range c {
+		c[i] = uint8(math.Round(float64(low[i]) + t*(float64(high[i])-float64(low[i]))))
+	}
+	return c
+}
+
+// rgb returns the attributes for a 24 bit foreground color
+func rgb(c [3]uint8) []color.Attribute {
+	return []color.Attribute{38, 2, color.Attribute(c[0]), color.Attribute(c[1]), color.Attribute(c[2])}
+}
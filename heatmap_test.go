@@ -0,0 +1,41 @@
+package gree
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestHeatmap(t *testing.T) {
+	a := NewNode("0")
+	a.NewChild("50")
+	a.NewChild("100").SetColor(color.FgRed)
+	size := func(node *Node) float64 {
+		v, _ := strconv.ParseFloat(node.String(), 64)
+		return v
+	}
+	a.Heatmap(size, [3]uint8{0, 0, 255}, [3]uint8{255, 0, 0})
+	expected := map[string][]color.Attribute{
+		"0":   {38, 2, 0, 0, 255},
+		"50":  {38, 2, 128, 0, 128},
+		"100": {38, 2, 255, 0, 0},
+	}
+	a.Walk(func(node *Node) bool {
+		if got := node.colorsApplied; len(got) != 1 || !reflect.DeepEqual(got[0], expected[node.String()]) {
+			t.Errorf("node %s, expected %v, got %v", node, expected[node.String()], got)
+		}
+		return true
+	})
+}
+
+func TestHeatmapEqualValues(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1")
+	a.Heatmap(func(*Node) float64 { return 7 }, [3]uint8{0, 0, 0}, [3]uint8{200, 100, 50})
+	expected := []color.Attribute{38, 2, 100, 50, 25}
+	if got := a.GetChild(0).colorsApplied[0]; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected midpoint %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,176 @@
+package gree
+
+import (
+	"errors"
+)
+
+type opKind int
+
+const (
+	opAdd opKind = iota
+	opRemove
+	opMove
+	opSetContents
+)
+
+// journalOp holds enough state to reverse or replay
+// a single structural mutation
+type journalOp struct {
+	kind        opKind
+	node        *Node
+	id          string
+	oldParent   *Node
+	newParent   *Node
+	oldIndex    int
+	newIndex    int
+	oldContents string
+	newContents string
+}
+
+type journal struct {
+	undo      []journalOp
+	redo      []journalOp
+	replaying bool
+}
+
+// record adds an op to the undo stack. Any new
+// mutation invalidates the redo stack.
+func (j *journal) record(op journalOp) {
+	if j.replaying {
+		return
+	}
+	j.undo = append(j.undo, op)
+	j.redo = nil
+}
+
+// EnableJournal starts recording AddChild, RemoveChild,
+// MoveTo and SetContents calls made anywhere in the tree
+// beneath this Node so they can be reversed with Undo and
+// replayed with Redo. It is meant to be called on the root.
+// Calling it again discards any recorded history.
+func (n *Node) EnableJournal() {
+	n.journal = &journal{}
+}
+
+// findJournal returns the journal of the nearest ancestor
+// (including this Node) that has one enabled
+func (n *Node) findJournal() *journal {
+	for p := n; p != nil; p = p.parent {
+		if p.journal != nil {
+			return p.journal
+		}
+	}
+	return nil
+}
+
+// errChangedOutside is returned when a node was moved by a
+// change the journal didn't record, so undoing or redoing an
+// op would take it from the wrong place
+var errChangedOutside = errors.New("node was moved outside of the journaled tree")
+
+// canUndo returns an error when op.node is no longer where
+// op left it
+func (op journalOp) canUndo() error {
+	switch op.kind {
+	case opAdd, opMove:
+		if op.newParent.childIndex(op.node) < 0 {
+			return errChangedOutside
+		}
+	case opRemove:
+		if op.node.parent != nil {
+			return errChangedOutside
+		}
+	}
+	return nil
+}
+
+// canRedo returns an error when op.node is no longer where
+// undoing op left it
+func (op journalOp) canRedo() error {
+	switch op.kind {
+	case opAdd:
+		if op.node.parent != nil {
+			return errChangedOutside
+		}
+	case opRemove, opMove:
+		if op.oldParent == nil && op.node.parent != nil {
+			return errChangedOutside
+		}
+		if op.oldParent != nil && op.oldParent.childIndex(op.node) < 0 {
+			return errChangedOutside
+		}
+	}
+	return nil
+}
+
+// Undo reverses the most recent recorded mutation. An error
+// is returned if no journal is enabled, there is nothing
+// left to undo or the node it changed has since been moved
+// by a change that wasn't recorded, in which case the
+// mutation is left to undo.
+func (n *Node) Undo() error {
+	j := n.findJournal()
+	if j == nil {
+		return errors.New("journal not enabled")
+	}
+	if len(j.undo) == 0 {
+		return errors.New("nothing to undo")
+	}
+	op := j.undo[len(j.undo)-1]
+	if err := op.canUndo(); err != nil {
+		return err
+	}
+	j.undo = j.undo[:len(j.undo)-1]
+	j.replaying = true
+	defer func() { j.replaying = false }()
+	switch op.kind {
+	case opAdd:
+		op.newParent.removeChildAt(op.newParent.childIndex(op.node))
+	case opRemove:
+		op.oldParent.insertChild(op.oldIndex, op.node)
+	case opMove:
+		op.newParent.removeChildAt(op.newParent.childIndex(op.node))
+		if op.oldParent != nil {
+			op.oldParent.insertChild(op.oldIndex, op.node)
+		}
+	case opSetContents:
+		op.node.SetContents(op.oldContents)
+	}
+	j.redo = append(j.redo, op)
+	return nil
+}
+
+// Redo replays the most recently undone mutation. An error
+// is returned like for Undo if no journal is enabled, there
+// is nothing left to redo or the node has since been moved.
+func (n *Node) Redo() error {
+	j := n.findJournal()
+	if j == nil {
+		return errors.New("journal not enabled")
+	}
+	if len(j.redo) == 0 {
+		return errors.New("nothing to redo")
+	}
+	op := j.redo[len(j.redo)-1]
+	if err := op.canRedo(); err != nil {
+		return err
+	}
+	j.redo = j.redo[:len(j.redo)-1]
+	j.replaying = true
+	defer func() { j.replaying = false }()
+	switch op.kind {
+	case opAdd:
+		op.newParent.insertChild(op.newIndex, op.node)
+	case opRemove:
+		op.oldParent.removeChildAt(op.oldParent.childIndex(op.node))
+	case opMove:
+		if op.oldParent != nil {
+			op.oldParent.removeChildAt(op.oldParent.childIndex(op.node))
+		}
+		op.newParent.insertChild(op.newIndex, op.node)
+	case opSetContents:
+		op.node.SetContents(op.newContents)
+	}
+	j.undo = append(j.undo, op)
+	return nil
+}
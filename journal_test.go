@@ -0,0 +1,91 @@
+package gree
+
+import (
+	"testing"
+)
+
+func TestJournalUndoRedo(t *testing.T) {
+	a := NewNode("root")
+	a.EnableJournal()
+	c1 := a.NewChild("child1")
+	c2 := a.NewChild("child2")
+	c2.NewChild("grandchild1")
+	c1.SetContents("renamed")
+	if err := c2.MoveTo(c1); err != nil {
+		t.Fatalf("unexpected error moving node: %s", err)
+	}
+	removed := a.RemoveChild(0)
+	if removed != c1 || a.NumChildren() != 0 {
+		t.Fatalf("expected child1 to be removed, got %v", removed)
+	}
+	// undo remove, move, rename in that order
+	for i := 0; i < 3; i++ {
+		if err := a.Undo(); err != nil {
+			t.Fatalf("unexpected error on undo %d: %s", i, err)
+		}
+	}
+	if a.NumChildren() != 2 || a.GetChild(0) != c1 || a.GetChild(1) != c2 {
+		t.Errorf("expected original children restored in order")
+	}
+	if c1.String() != "child1" {
+		t.Errorf("expected contents 'child1', got '%s'", c1.String())
+	}
+	if c2.GetChild(0).GetDepth() != 2 {
+		t.Errorf("expected grandchild depth 2, got %d", c2.GetChild(0).GetDepth())
+	}
+	// redo the rename and the move
+	for i := 0; i < 2; i++ {
+		if err := a.Redo(); err != nil {
+			t.Fatalf("unexpected error on redo %d: %s", i, err)
+		}
+	}
+	if c1.String() != "renamed" || c2.parent != c1 || c2.GetDepth() != 2 {
+		t.Errorf("expected rename and move to be replayed")
+	}
+	// a new mutation clears the redo history
+	a.NewChild("child3")
+	if err := a.Redo(); err == nil {
+		t.Errorf("expected error redoing after a new mutation")
+	}
+}
+
+func TestJournalChangedOutside(t *testing.T) {
+	a := NewNode("root")
+	a.EnableJournal()
+	b := a.NewChild("child1")
+	c := a.NewChild("child2")
+	other := NewNode("other")
+	a.RemoveChild(1)
+	other.AddChild(c)
+	if err := a.Undo(); err == nil {
+		t.Errorf("expected error undoing the removal of a node added elsewhere")
+	}
+	if a.NumChildren() != 1 || c.parent != other {
+		t.Errorf("expected the trees to be left as is")
+	}
+	other.AddChild(b)
+	other.RemoveChild(1)
+	if err := a.Undo(); err == nil {
+		t.Errorf("expected error undoing the move of a node removed elsewhere")
+	}
+	if other.NumChildren() != 1 || b.parent != nil {
+		t.Errorf("expected the trees to be left as is")
+	}
+}
+
+func TestJournalNotEnabled(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1")
+	if err := a.Undo(); err == nil {
+		t.Errorf("expected error when journal not enabled")
+	}
+}
+
+func TestMoveToCycle(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	c := b.NewChild("grandchild1")
+	if err := b.MoveTo(c); err == nil {
+		t.Errorf("expected error moving node beneath its own descendent")
+	}
+}
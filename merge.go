@@ -0,0 +1,37 @@
+package gree
+
+// Merge adds the descendents of other to this node, matching
+// children by contents. A child of other whose contents match
+// a child of this node is merged into it, otherwise a clone of
+// it is added as a new child. The contents of this node and
+// other themselves aren't compared and other is left as is.
+func (n *Node) Merge(other *Node) {
+	n.MergeBy(other, func(node *Node) string {
+		return node.contents
+	})
+}
+
+// MergeBy is like Merge but matches children by the string
+// key returns for them, e.g. lower cased contents to ignore
+// cosmetic differences, or an id kept in Data. When several
+// children of this node have the same key the first is used.
+func (n *Node) MergeBy(other *Node, key func(*Node) string) {
+	if n == nil || other == nil {
+		return
+	}
+	existing := make(map[string]*Node, len(n.children))
+	for _, child := range n.children {
+		k := key(child)
+		if _, ok := existing[k]; !ok {
+			existing[k] = child
+		}
+	}
+	for _, child := range other.children {
+		k := key(child)
+		if match, ok := existing[k]; ok {
+			match.MergeBy(child, key)
+			continue
+		}
+		existing[k] = n.AddChild(child.Clone())
+	}
+}
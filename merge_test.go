@@ -0,0 +1,49 @@
+package gree
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("src").NewChild("main.go")
+	a.NewChild("docs")
+	b := NewNode("root")
+	src := b.NewChild("src")
+	src.NewChild("main.go").NewChild("func main")
+	src.NewChild("util.go")
+	b.NewChild("test")
+	a.Merge(b)
+	expected := "root(src(main.go(func main), util.go), docs, test)"
+	if got := a.Summary(); got != expected {
+		t.Errorf("expected '%s', got '%s'", expected, got)
+	}
+	if got := b.Summary(); got != "root(src(main.go(func main), util.go), test)" {
+		t.Errorf("expected other to be left as is, got '%s'", got)
+	}
+	a.GetChild(2).NewChild("added")
+	if b.GetChild(1).NumChildren() != 0 {
+		t.Errorf("expected merged children to be clones")
+	}
+}
+
+func TestMergeBy(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("Src").NewChild("main.go")
+	b := NewNode("root")
+	b.NewChild("src").NewChild("util.go")
+	b.NewChild("SRC").NewChild("lib.go")
+	a.MergeBy(b, func(n *Node) string {
+		return strings.ToLower(n.String())
+	})
+	expected := "root(Src(main.go, util.go, lib.go))"
+	if got := a.Summary(); got != expected {
+		t.Errorf("expected '%s', got '%s'", expected, got)
+	}
+	a.Merge(b)
+	expected = "root(Src(main.go, util.go, lib.go), src(util.go), SRC(lib.go))"
+	if got := a.Summary(); got != expected {
+		t.Errorf("expected '%s', got '%s'", expected, got)
+	}
+}
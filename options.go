@@ -0,0 +1,64 @@
+package gree
+
+// DefaultDrawInput returns the default options, the same as
+// a zero DrawInput: no border and each node's own padding
+// ("   " unless set otherwise). Whether colors are output is
+// not a draw option, it's up to fatih/color's color.NoColor,
+// which is set when stdout isn't a terminal. Chain the With*
+// methods to change the options, e.g.
+//
+//	a.DrawOptions(gree.DefaultDrawInput().WithBorder().WithMaxWidth(80))
+func DefaultDrawInput() *DrawInput {
+	return &DrawInput{}
+}
+
+// WithBorder turns on the border
+func (di *DrawInput) WithBorder() *DrawInput {
+	di.Border = true
+	return di
+}
+
+// WithDebug turns on the debug ruler
+func (di *DrawInput) WithDebug() *DrawInput {
+	di.Debug = true
+	return di
+}
+
+// WithPadding sets the Padding used for every node
+func (di *DrawInput) WithPadding(padding string) *DrawInput {
+	di.Padding = padding
+	return di
+}
+
+// WithMaxWidth sets MaxWidth
+func (di *DrawInput) WithMaxWidth(width int) *DrawInput {
+	di.MaxWidth = width
+	return di
+}
+
+// WithFixedWidth sets FixedWidth
+func (di *DrawInput) WithFixedWidth(width int) *DrawInput {
+	di.FixedWidth = width
+	return di
+}
+
+// WithDepth sets MinDepth and MaxDepth
+func (di *DrawInput) WithDepth(min, max int) *DrawInput {
+	di.MinDepth = min
+	di.MaxDepth = max
+	return di
+}
+
+// WithStacked turns on the stacked layout
+func (di *DrawInput) WithStacked() *DrawInput {
+	di.Stacked = true
+	return di
+}
+
+// WithStatus turns on ShowStatus and, if color is set,
+// ColorStatus
+func (di *DrawInput) WithStatus(color bool) *DrawInput {
+	di.ShowStatus = true
+	di.ColorStatus = color
+	return di
+}
@@ -0,0 +1,34 @@
+package gree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDefaultDrawInput(t *testing.T) {
+	if got := DefaultDrawInput(); !reflect.DeepEqual(got, &DrawInput{}) {
+		t.Errorf("expected the zero options, got %+v", got)
+	}
+	got := DefaultDrawInput().WithBorder().WithDebug().WithPadding("  ").WithMaxWidth(80).
+		WithFixedWidth(60).WithDepth(1, 3).WithStacked().WithStatus(true)
+	expected := &DrawInput{
+		Border:      true,
+		Debug:       true,
+		Padding:     "  ",
+		MaxWidth:    80,
+		FixedWidth:  60,
+		MinDepth:    1,
+		MaxDepth:    3,
+		Stacked:     true,
+		ShowStatus:  true,
+		ColorStatus: true,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+	a := NewNode("root")
+	a.NewChild("child1")
+	if a.DrawOptions(DefaultDrawInput()) != NewNode("root").Add("child1").Draw() {
+		t.Errorf("expected DefaultDrawInput to draw like Draw")
+	}
+}
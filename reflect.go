@@ -0,0 +1,158 @@
+package gree
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// FromStruct builds a tree from a struct, slice, array or map
+// using reflection, which helps when debugging large values
+// such as configs. The root is labeled with the type name.
+// Exported struct fields become children labeled
+// "FieldName: value", slice and array elements are labeled
+// with their index (e.g., "[0]: value") and map entries with
+// their key, sorted. Nested structs, slices and maps become
+// children of their own. Pointers and interfaces are
+// followed, values implementing fmt.Stringer are drawn with
+// their String method and unexported fields are skipped. A
+// pointer, slice or map already being built further up the
+// tree is drawn as "(cycle)" instead of being followed.
+func FromStruct(v interface{}) (*Node, error) {
+	rv := reflect.ValueOf(v)
+	b := reflectBuilder{seen: make(map[uintptr]bool)}
+	for rv.IsValid() && (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) {
+		if rv.IsNil() {
+			return nil, errors.New("cannot build a tree from a nil pointer")
+		}
+		if rv.Kind() == reflect.Ptr {
+			b.enter(rv)
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil, errors.New("cannot build a tree from nil")
+	}
+	switch rv.Kind() {
+	case reflect.Struct, reflect.Slice, reflect.Array, reflect.Map:
+	default:
+		return nil, fmt.Errorf("cannot build a tree from a %s, expected a struct, slice, array or map", rv.Kind())
+	}
+	name := rv.Type().Name()
+	if name == "" {
+		name = rv.Type().String()
+	}
+	root := NewNode(name)
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Map {
+		b.enter(rv)
+	}
+	b.addChildren(root, rv)
+	return root, nil
+}
+
+// reflectBuilder keeps track of the references on the path
+// to the value being built so cycles can be detected
+type reflectBuilder struct {
+	seen map[uintptr]bool
+}
+
+// enter marks a reference as being built, returning false
+// when it already is further up the tree
+func (b *reflectBuilder) enter(v reflect.Value) bool {
+	p := v.Pointer()
+	if b.seen[p] {
+		return false
+	}
+	b.seen[p] = true
+	return true
+}
+
+// add adds the value v as a child of parent labeled name
+func (b *reflectBuilder) add(parent *Node, name string, v reflect.Value) {
+	for {
+		if s, ok := stringValue(v); ok {
+			parent.NewChild(name + ": " + s)
+			return
+		}
+		if v.Kind() != reflect.Ptr && v.Kind() != reflect.Interface {
+			break
+		}
+		if v.IsNil() {
+			parent.NewChild(name + ": nil")
+			return
+		}
+		if v.Kind() == reflect.Ptr {
+			if !b.enter(v) {
+				parent.NewChild(name + ": (cycle)")
+				return
+			}
+			defer delete(b.seen, v.Pointer())
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		if v.Len() == 0 {
+			parent.NewChild(name + ": " + fmt.Sprint(v.Interface()))
+			return
+		}
+		if !b.enter(v) {
+			parent.NewChild(name + ": (cycle)")
+			return
+		}
+		defer delete(b.seen, v.Pointer())
+		b.addChildren(parent.NewChild(name), v)
+	case reflect.Struct, reflect.Array:
+		b.addChildren(parent.NewChild(name), v)
+	default:
+		parent.NewChild(name + ": " + fmt.Sprint(v.Interface()))
+	}
+}
+
+// addChildren adds the fields, elements or entries of v
+// as children of node
+func (b *reflectBuilder) addChildren(node *Node, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if field := t.Field(i); field.IsExported() {
+				b.add(node, field.Name, v.Field(i))
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			b.add(node, fmt.Sprintf("[%d]", i), v.Index(i))
+		}
+	case reflect.Map:
+		type entry struct {
+			name  string
+			value reflect.Value
+		}
+		var entries []entry
+		for _, key := range v.MapKeys() {
+			entries = append(entries, entry{fmt.Sprint(key.Interface()), v.MapIndex(key)})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].name < entries[j].name
+		})
+		for _, e := range entries {
+			b.add(node, e.name, e.value)
+		}
+	}
+}
+
+// stringValue returns the String of v when it implements
+// fmt.Stringer and can be called safely
+func stringValue(v reflect.Value) (string, bool) {
+	if !v.IsValid() || !v.CanInterface() || !v.Type().Implements(stringerType) {
+		return "", false
+	}
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return "", false
+	}
+	return v.Interface().(fmt.Stringer).String(), true
+}
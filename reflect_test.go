@@ -0,0 +1,77 @@
+package gree
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type testServer struct {
+	Name    string
+	Port    int
+	Tags    []string
+	Limits  map[string]int
+	Timeout time.Duration
+	Backup  *testServer
+	Extra   interface{}
+	secret  string
+}
+
+func TestFromStruct(t *testing.T) {
+	s := testServer{
+		Name:    "web",
+		Port:    8080,
+		Tags:    []string{"a", "b"},
+		Limits:  map[string]int{"rps": 100, "conn": 10},
+		Timeout: 2 * time.Second,
+		Backup:  &testServer{Name: "spare"},
+		secret:  "hidden",
+	}
+	root, err := FromStruct(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "testServer(Name: web, Port: 8080, Tags([0]: a, [1]: b), " +
+		"Limits(conn: 10, rps: 100), Timeout: 2s, " +
+		"Backup(Name: spare, Port: 0, Tags: [], Limits: map[], Timeout: 0s, Backup: nil, Extra: nil), " +
+		"Extra: nil)"
+	if got := root.Summary(); got != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, got)
+	}
+	if strings.Contains(root.Draw(), "hidden") {
+		t.Errorf("expected unexported fields to be skipped")
+	}
+}
+
+func TestFromStructCycle(t *testing.T) {
+	s := &testServer{Name: "loop"}
+	s.Backup = s
+	m := map[string]interface{}{"k": 1}
+	m["self"] = m
+	s.Extra = m
+	root, err := FromStruct(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "testServer(Name: loop, Port: 0, Tags: [], Limits: map[], Timeout: 0s, " +
+		`Backup: \(cycle\), Extra(k: 1, self: \(cycle\)))`
+	if got := root.Summary(); got != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, got)
+	}
+}
+
+func TestFromStructErrors(t *testing.T) {
+	var s *testServer
+	for _, v := range []interface{}{nil, s, 42} {
+		if _, err := FromStruct(v); err == nil {
+			t.Errorf("expected error building from %v", v)
+		}
+	}
+	root, err := FromStruct([]int{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := root.Summary(); got != "[]int([0]: 1, [1]: 2)" {
+		t.Errorf("expected slice root, got %s", got)
+	}
+}
@@ -0,0 +1,189 @@
+package gree
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// path returns this node and all of its ancestors
+// ordered from the root down to this node
+func (n *Node) path() (path []*Node) {
+	for p := n; p != nil; p = p.parent {
+		path = append([]*Node{p}, path...)
+	}
+	return path
+}
+
+// Contains reports whether other is this node or one of its
+// descendents. It follows other's parents so no draw is needed.
+func (n *Node) Contains(other *Node) bool {
+	for p := other; p != nil; p = p.parent {
+		if p == n {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDescendantOf reports whether ancestor is a parent, grand
+// parent, etc. of this node. A node is not its own descendant.
+func (n *Node) IsDescendantOf(ancestor *Node) bool {
+	return n != ancestor && ancestor.Contains(n)
+}
+
+// FindCommonAncestor returns the deepest node that is an
+// ancestor of (or equal to) every passed node. It returns
+// nil if no nodes are passed or they are not all in the
+// same tree.
+func FindCommonAncestor(nodes ...*Node) *Node {
+	if len(nodes) == 0 || nodes[0] == nil {
+		return nil
+	}
+	common := nodes[0].path()
+	for _, node := range nodes[1:] {
+		if node == nil {
+			return nil
+		}
+		path := node.path()
+		i := 0
+		for i < len(common) && i < len(path) && common[i] == path[i] {
+			i++
+		}
+		common = common[:i]
+		if len(common) == 0 {
+			return nil
+		}
+	}
+	return common[len(common)-1]
+}
+
+// PathBetween returns the nodes on the way from a up to the
+// common ancestor of a and b and back down to b, including a
+// and b. It returns nil if they are not in the same tree.
+func PathBetween(a, b *Node) []*Node {
+	ancestor := FindCommonAncestor(a, b)
+	if ancestor == nil {
+		return nil
+	}
+	var path []*Node
+	for p := a; p != ancestor; p = p.parent {
+		path = append(path, p)
+	}
+	path = append(path, ancestor)
+	var down []*Node
+	for p := b; p != ancestor; p = p.parent {
+		down = append([]*Node{p}, down...)
+	}
+	return append(path, down...)
+}
+
+// SpanningSubtree returns a cloned tree holding exactly the
+// passed nodes plus the ancestors needed to connect them to
+// their common ancestor, which becomes the root of the clone.
+// Children that were not selected are left out. An error is
+// returned if no nodes are passed or they are not all in the
+// same tree.
+func SpanningSubtree(nodes []*Node) (*Node, error) {
+	if len(nodes) == 0 {
+		return nil, errors.New("no nodes passed")
+	}
+	root := FindCommonAncestor(nodes...)
+	if root == nil {
+		return nil, errors.New("nodes are not all in the same tree")
+	}
+	keep := make(map[*Node]bool)
+	for _, node := range nodes {
+		for p := node; p != root.parent; p = p.parent {
+			keep[p] = true
+		}
+	}
+	return root.cloneWhere(keep, false), nil
+}
+
+// cloneWhere copies this node and the descendents in keep,
+// with linkedCopy when linked is set
+func (n *Node) cloneWhere(keep map[*Node]bool, linked bool) *Node {
+	var nn *Node
+	if linked {
+		nn = n.linkedCopy()
+	} else {
+		nn = n.copyNode(false)
+	}
+	for _, child := range n.children {
+		if keep[child] {
+			nn.insertChild(len(nn.children), child.cloneWhere(keep, linked))
+		}
+	}
+	return nn
+}
+
+// ExtractWhere returns a clone of the subtree of every node
+// for which match returns true, each a new root, in draw
+// order. Matches nested in another match are returned as
+// well as being part of the outer clone. The tree is left as
+// is and the clones can be changed safely.
+func (n *Node) ExtractWhere(match func(*Node) bool) (extracted []*Node) {
+	n.Walk(func(node *Node) bool {
+		if match(node) {
+			extracted = append(extracted, node.Clone())
+		}
+		return true
+	})
+	return extracted
+}
+
+// DrawPathTo draws only the nodes on the way from this node
+// down to target, leaving out every other branch, with the
+// label of target in reverse video. It returns an empty string
+// when target is not this node or one of its descendents.
+func (n *Node) DrawPathTo(target *Node, di *DrawInput) string {
+	if n == nil || !n.Contains(target) {
+		return ""
+	}
+	keep := make(map[*Node]bool)
+	for p := target; p != n.parent; p = p.parent {
+		keep[p] = true
+	}
+	view := n.cloneWhere(keep, true)
+	last := view
+	for len(last.children) > 0 {
+		last = last.children[0]
+	}
+	last.SetColors(color.ReverseVideo)
+	lines, trailer := n.drawCopy(view, di)
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(line.text)
+		b.WriteString("\n")
+	}
+	b.WriteString(trailer)
+	return b.String()
+}
+
+// refEscaper escapes the characters Ref uses for structure
+var refEscaper = strings.NewReplacer(`\`, `\\`, `>`, `\>`, `[`, `\[`)
+
+// Ref returns a short reference to this node for messages,
+// e.g. "root>child2>grandchild1[#2]": the contents of the
+// nodes from the root down to this one joined by ">", then
+// the position of this node among its siblings (the index to
+// pass to GetChild) as "[#2]". A root has no position. In
+// contents, backslashes, ">" and "[" are escaped with a
+// backslash so the reference can be split reliably.
+func (n *Node) Ref() string {
+	if n == nil {
+		return ""
+	}
+	var parts []string
+	for _, p := range n.path() {
+		parts = append(parts, refEscaper.Replace(p.contents))
+	}
+	ref := strings.Join(parts, ">")
+	if n.parent != nil {
+		ref += fmt.Sprintf("[#%d]", n.parent.childIndex(n))
+	}
+	return ref
+}
@@ -0,0 +1,198 @@
+package gree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestFindCommonAncestor(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	c := b.NewChild("grandchild1")
+	d := b.NewChild("grandchild2")
+	e := a.NewChild("child2")
+	if got := FindCommonAncestor(c, d); got != b {
+		t.Errorf("expected child1, got %v", got)
+	}
+	if got := FindCommonAncestor(c, e); got != a {
+		t.Errorf("expected root, got %v", got)
+	}
+	if got := FindCommonAncestor(c, b); got != b {
+		t.Errorf("expected child1, got %v", got)
+	}
+	if got := FindCommonAncestor(c, NewNode("other")); got != nil {
+		t.Errorf("expected nil for nodes in different trees, got %v", got)
+	}
+}
+
+func TestSpanningSubtree(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	c := b.NewChild("grandchild1")
+	b.NewChild("grandchild2")
+	e := a.NewChild("child2")
+	e.NewChild("grandchild3")
+	a.NewChild("child3")
+	got, err := SpanningSubtree([]*Node{c, e})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := []string{
+		"root",
+		"├── child1",
+		"│   └── grandchild1",
+		"└── child2",
+	}
+	lines := strings.Split(got.Draw(), "\n")
+	for i, ex := range expected {
+		if strings.TrimRight(lines[i], " ") != ex {
+			t.Errorf("line %d, expected '%s', got '%s'", i, ex, lines[i])
+		}
+	}
+	if got == a || a.NumChildren() != 3 {
+		t.Errorf("expected a clone with the original left untouched")
+	}
+	if _, err := SpanningSubtree([]*Node{c, NewNode("other")}); err == nil {
+		t.Errorf("expected error for nodes in different trees")
+	}
+}
+
+func TestContains(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	g := b.NewChild("grandchild1")
+	c := a.NewChild("child2")
+	if !a.Contains(g) || !b.Contains(g) || !g.Contains(g) {
+		t.Errorf("expected ancestors and self to contain grandchild1")
+	}
+	if c.Contains(g) || g.Contains(b) || a.Contains(NewNode("other")) {
+		t.Errorf("expected unrelated nodes not to be contained")
+	}
+	if !g.IsDescendantOf(a) || !g.IsDescendantOf(b) {
+		t.Errorf("expected grandchild1 to descend from its ancestors")
+	}
+	if g.IsDescendantOf(g) || b.IsDescendantOf(g) || g.IsDescendantOf(c) {
+		t.Errorf("expected IsDescendantOf to be false for self, descendents and cousins")
+	}
+}
+
+func TestPathBetween(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	g1 := b.NewChild("grandchild1")
+	g2 := b.NewChild("grandchild2")
+	c := a.NewChild("child2")
+	g3 := c.NewChild("grandchild3")
+	tests := []struct {
+		from, to *Node
+		expected []*Node
+	}{
+		{g1, g3, []*Node{g1, b, a, c, g3}},
+		{g1, g2, []*Node{g1, b, g2}},
+		{a, g2, []*Node{a, b, g2}},
+		{g3, c, []*Node{g3, c}},
+		{b, b, []*Node{b}},
+		{g1, NewNode("other"), nil},
+	}
+	for _, test := range tests {
+		got := PathBetween(test.from, test.to)
+		if len(got) != len(test.expected) {
+			t.Errorf("%s to %s, expected %v, got %v", test.from, test.to, test.expected, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.expected[i] {
+				t.Errorf("%s to %s, expected %v, got %v", test.from, test.to, test.expected, got)
+				break
+			}
+		}
+	}
+}
+
+func TestExtractWhere(t *testing.T) {
+	a := NewNode("root")
+	m1 := a.NewChild("module1")
+	m1.NewChild("file1")
+	m1.NewChild("module2").NewChild("file2")
+	a.NewChild("file3")
+	got := a.ExtractWhere(func(n *Node) bool {
+		return strings.HasPrefix(n.String(), "module")
+	})
+	expected := []string{"module1(file1, module2(file2))", "module2(file2)"}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d subtrees, got %d", len(expected), len(got))
+	}
+	for i, e := range expected {
+		if got[i].Summary() != e {
+			t.Errorf("expected '%s', got '%s'", e, got[i].Summary())
+		}
+		if got[i].parent != nil {
+			t.Errorf("expected '%s' to be a new root", got[i])
+		}
+	}
+	got[0].NewChild("added")
+	if m1.NumChildren() != 2 || m1.parent != a {
+		t.Errorf("expected original tree to be untouched")
+	}
+}
+
+func TestDrawPathTo(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	b.NewChild("grandchild1")
+	g := b.NewChild("grandchild2")
+	g.NewChild("greatgrandchild1")
+	a.NewChild("child2").NewChild("grandchild3")
+	got := strings.Split(a.DrawPathTo(g, &DrawInput{}), "\n")
+	expected := []string{
+		"root",
+		"└── child1",
+		"    └── " + color.New(color.ReverseVideo).Sprint("grandchild2"),
+		"",
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d lines, got %q", len(expected), got)
+	}
+	for i, e := range expected {
+		if strings.TrimRight(got[i], " ") != e {
+			t.Errorf("line %d, expected %q, got %q", i, e, got[i])
+		}
+	}
+	if a.NumChildren() != 2 || len(g.colorsApplied) != 0 {
+		t.Errorf("expected the tree to be left as is")
+	}
+	if got := b.DrawPathTo(NewNode("other"), &DrawInput{}); got != "" {
+		t.Errorf("expected empty output for a node outside the tree, got %q", got)
+	}
+	color.NoColor = true
+	got = strings.Split(a.DrawPathTo(g, &DrawInput{ShowIndex: true}), "\n")
+	if line := strings.TrimRight(got[2], " "); line != "    └── [1] grandchild2" {
+		t.Errorf("expected the index in the whole tree, got %q", line)
+	}
+}
+
+func TestRef(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1")
+	b := a.NewChild("child2")
+	b.NewChild("grandchild1")
+	b.NewChild("grandchild1")
+	g := b.NewChild("grandchild1")
+	odd := a.NewChild(`a>b[c]\d`)
+	tests := map[*Node]string{
+		a:   "root",
+		b:   "root>child2[#1]",
+		g:   "root>child2>grandchild1[#2]",
+		odd: `root>a\>b\[c]\\d[#2]`,
+	}
+	for node, expected := range tests {
+		if got := node.Ref(); got != expected {
+			t.Errorf("expected '%s', got '%s'", expected, got)
+		}
+	}
+}
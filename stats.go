@@ -0,0 +1,101 @@
+package gree
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TreeStats describes the shape of a tree, see Stats
+type TreeStats struct {
+	// NodeCount is the number of nodes including the root
+	NodeCount int
+	// LeafCount is the number of nodes without children
+	LeafCount int
+	// MaxDepth is the same as the MaxDepth method, the number
+	// of edges on the longest path from the root to a leaf
+	MaxDepth int
+	// MaxBreadth is the number of nodes in the largest
+	// generation
+	MaxBreadth int
+	// AvgBranching is the average number of children of the
+	// nodes that have any, 0 for a single node
+	AvgBranching float64
+	// DepthCounts holds the number of nodes at each depth
+	// relative to the root, starting with 1 for the root
+	DepthCounts []int
+}
+
+// Stats returns statistics of this node and its descendents
+// gathered in a single traversal
+func (n *Node) Stats() (stats TreeStats) {
+	if n == nil {
+		return stats
+	}
+	parents := 0
+	for gen := []*Node{n}; len(gen) > 0; {
+		stats.DepthCounts = append(stats.DepthCounts, len(gen))
+		if len(gen) > stats.MaxBreadth {
+			stats.MaxBreadth = len(gen)
+		}
+		var next []*Node
+		for _, node := range gen {
+			stats.NodeCount++
+			if len(node.children) == 0 {
+				stats.LeafCount++
+				continue
+			}
+			parents++
+			next = append(next, node.children...)
+		}
+		gen = next
+	}
+	stats.MaxDepth = len(stats.DepthCounts) - 1
+	if parents > 0 {
+		stats.AvgBranching = float64(stats.NodeCount-1) / float64(parents)
+	}
+	return stats
+}
+
+// summarizeBelow replaces the children of the nodes depth
+// generations beneath this node with a single node counting
+// the descendents it replaces
+func (n *Node) summarizeBelow(depth int) {
+	if depth > 0 {
+		for _, child := range n.children {
+			child.summarizeBelow(depth - 1)
+		}
+		return
+	}
+	if len(n.children) == 0 {
+		return
+	}
+	stats := n.Stats()
+	for len(n.children) > 0 {
+		n.removeChildAt(0)
+	}
+	summary := NewNode(fmt.Sprintf("... (%s, %s)",
+		plural(stats.NodeCount-1, "node"), plural(stats.LeafCount, "leaf")))
+	summary.summarized = true
+	n.insertChild(0, summary)
+}
+
+// plural formats count followed by noun, pluralized
+func plural(count int, noun string) string {
+	if count == 1 {
+		return "1 " + noun
+	}
+	if strings.HasSuffix(noun, "f") {
+		noun = strings.TrimSuffix(noun, "f") + "ve"
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
+
+// drawSummarized draws a copy of this tree with everything
+// deeper than di.SummarizeDeeperThan replaced by counts
+func (n *Node) drawSummarized(di *DrawInput) (lines []drawnLine, trailer string) {
+	view := n.linkedClone()
+	view.summarizeBelow(di.SummarizeDeeperThan)
+	sub := *di
+	sub.SummarizeDeeperThan = 0
+	return n.drawCopy(view, &sub)
+}
@@ -0,0 +1,88 @@
+package gree
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	b.NewChild("grandchild1")
+	b.NewChild("grandchild2")
+	b.NewChild("grandchild3").NewChild("greatgrandchild1")
+	a.NewChild("child2")
+	expected := TreeStats{
+		NodeCount:    7,
+		LeafCount:    4,
+		MaxDepth:     3,
+		MaxBreadth:   3,
+		AvgBranching: 2,
+		DepthCounts:  []int{1, 2, 3, 1},
+	}
+	if got := a.Stats(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+	if got := NewNode("leaf").Stats(); got.NodeCount != 1 || got.LeafCount != 1 || got.AvgBranching != 0 {
+		t.Errorf("unexpected stats for a single node %+v", got)
+	}
+}
+
+func TestSummarizeDeeperThan(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	g := b.NewChild("grandchild1")
+	g.NewChild("greatgrandchild1")
+	g.NewChild("greatgrandchild2")
+	b.NewChild("grandchild2")
+	c := a.NewChild("child2")
+	c.NewChild("grandchild3")
+	a.NewChild("child3")
+	di := &DrawInput{SummarizeDeeperThan: 1}
+	got := a.DrawLines(di)
+	expected := []string{
+		"root",
+		"├── child1",
+		"│   └── ... (4 nodes, 3 leaves)",
+		"├── child2",
+		"│   └── ... (1 node, 1 leaf)",
+		"└── child3",
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d lines, got %d:\n%s", len(expected), len(got), strings.Join(got, "\n"))
+	}
+	for i, e := range expected {
+		if strings.TrimRight(got[i], " ") != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+		}
+	}
+	rows := a.RenderRowIndex(di)
+	if rows[1] != b || rows[2] != nil {
+		t.Errorf("expected summary rows not to map to a node, got %v", rows)
+	}
+	if g.NumChildren() != 2 {
+		t.Errorf("expected original tree to be untouched")
+	}
+	di.ShowIndex = true
+	if got := c.DrawLines(di)[0]; !strings.HasPrefix(got, "[1] child2") {
+		t.Errorf("expected the root to keep its index, got '%s'", got)
+	}
+	di.ShowStatus = true
+	di.PrefixFunc = func(*Node) string { return "<" }
+	di.SuffixFunc = func(*Node) string { return ">" }
+	c.SetStatus(Success)
+	expected = []string{
+		"<root>",
+		"├── [0] <child1>",
+		"│   └── ... (4 nodes, 3 leaves)",
+		"├── [1] ✓ <child2>",
+		"│   └── ... (1 node, 1 leaf)",
+		"└── [2] <child3>",
+	}
+	for i, got := range a.DrawLines(di) {
+		if strings.TrimRight(got, " ") != expected[i] {
+			t.Errorf("line %d, expected '%s', got '%s'", i, expected[i], got)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package gree
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+)
+
+// Status is the state of the work a node represents, e.g.
+// a step in a build pipeline
+type Status int
+
+const (
+	NoStatus Status = iota // default, nothing is rendered
+	Pending
+	Running
+	Success
+	Failure
+	Skipped
+)
+
+// StatusStyle is how a Status is rendered when
+// DrawInput.ShowStatus is set
+type StatusStyle struct {
+	Glyph  string            // rendered before the label
+	Colors []color.Attribute // applied to the label with DrawInput.ColorStatus
+}
+
+// DefaultStatusTheme holds the styles used for statuses
+// that are not overridden by DrawInput.StatusTheme
+var DefaultStatusTheme = map[Status]StatusStyle{
+	Pending: {Glyph: "⏳", Colors: []color.Attribute{color.FgHiBlack}},
+	Running: {Glyph: "▶", Colors: []color.Attribute{color.FgCyan}},
+	Success: {Glyph: "✓", Colors: []color.Attribute{color.FgGreen}},
+	Failure: {Glyph: "✗", Colors: []color.Attribute{color.FgRed}},
+	Skipped: {Glyph: "⊘", Colors: []color.Attribute{color.FgYellow}},
+}
+
+// SetStatus sets the status of this node
+func (n *Node) SetStatus(s Status) *Node {
+	n.status = s
+	return n
+}
+
+// GetStatus returns the status of this node
+func (n *Node) GetStatus() Status {
+	if n == nil {
+		return NoStatus
+	}
+	return n.status
+}
+
+func (di *DrawInput) statusStyle(s Status) StatusStyle {
+	if style, ok := di.StatusTheme[s]; ok {
+		return style
+	}
+	return DefaultStatusTheme[s]
+}
+
+var statusNames = map[Status]string{
+	NoStatus: "",
+	Pending:  "pending",
+	Running:  "running",
+	Success:  "success",
+	Failure:  "failure",
+	Skipped:  "skipped",
+}
+
+// String returns the lowercase name of the status, or an
+// empty string for NoStatus
+func (s Status) String() string {
+	return statusNames[s]
+}
+
+// MarshalText encodes the status as its name
+func (s Status) MarshalText() ([]byte, error) {
+	name, ok := statusNames[s]
+	if !ok {
+		return nil, fmt.Errorf("unknown status %d", int(s))
+	}
+	return []byte(name), nil
+}
+
+// UnmarshalText decodes a status from its name
+func (s *Status) UnmarshalText(text []byte) error {
+	for status, name := range statusNames {
+		if name == string(text) {
+			*s = status
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown status '%s'", string(text))
+}
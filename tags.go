@@ -0,0 +1,68 @@
+package gree
+
+import (
+	"sort"
+
+	"github.com/fatih/color"
+)
+
+// AddTag tags this node with tag. A node can have any number
+// of tags, they are never rendered unless DrawInput.TagColors
+// is set.
+func (n *Node) AddTag(tag string) *Node {
+	if n.tags == nil {
+		n.tags = make(map[string]bool)
+	}
+	n.tags[tag] = true
+	return n
+}
+
+// RemoveTag removes tag from this node
+func (n *Node) RemoveTag(tag string) *Node {
+	delete(n.tags, tag)
+	return n
+}
+
+// HasTag reports whether this node is tagged with tag
+func (n *Node) HasTag(tag string) bool {
+	if n == nil {
+		return false
+	}
+	return n.tags[tag]
+}
+
+// Tags returns the tags of this node sorted
+func (n *Node) Tags() []string {
+	if n == nil {
+		return nil
+	}
+	tags := make([]string, 0, len(n.tags))
+	for tag := range n.tags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// SelectByTag returns this node and its descendents that are
+// tagged with tag, in the same order as Walk
+func (n *Node) SelectByTag(tag string) (selected []*Node) {
+	n.Walk(func(node *Node) bool {
+		if node.HasTag(tag) {
+			selected = append(selected, node)
+		}
+		return true
+	})
+	return selected
+}
+
+// tagColors returns the DrawInput.TagColors of this node's
+// tags in tag order
+func (n *Node) tagColors(di *DrawInput) (attrs []color.Attribute) {
+	for _, tag := range n.Tags() {
+		if attr, ok := di.TagColors[tag]; ok {
+			attrs = append(attrs, attr)
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,52 @@
+package gree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestTags(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1").AddTag("error").AddTag("retryable")
+	c := a.NewChild("child2").AddTag("error")
+	b.NewChild("grandchild1").AddTag("retryable")
+	if !b.HasTag("error") || a.HasTag("error") {
+		t.Errorf("unexpected HasTag results")
+	}
+	if got := strings.Join(b.Tags(), ","); got != "error,retryable" {
+		t.Errorf("expected sorted tags, got '%s'", got)
+	}
+	if got := a.SelectByTag("error"); len(got) != 2 || got[0] != b || got[1] != c {
+		t.Errorf("expected child1 and child2, got %v", got)
+	}
+	c.RemoveTag("error")
+	if got := a.SelectByTag("error"); len(got) != 1 {
+		t.Errorf("expected child2 to be untagged, got %v", got)
+	}
+	clone := a.Clone()
+	if got := clone.SelectByTag("retryable"); len(got) != 2 {
+		t.Errorf("expected tags to survive Clone, got %v", got)
+	}
+	clone.GetChild(0).RemoveTag("retryable")
+	if !b.HasTag("retryable") {
+		t.Errorf("expected clone tags to be independent")
+	}
+}
+
+func TestTagColors(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+	a := NewNode("root")
+	a.NewChild("child1").AddTag("error")
+	a.NewChild("child2").AddTag("error").SetColor(color.FgBlue)
+	lines := strings.Split(a.DrawOptions(&DrawInput{TagColors: map[string]color.Attribute{"error": color.FgRed}}), "\n")
+	if expected := "├── " + color.New(color.FgRed).Sprint("child1"); strings.TrimRight(lines[1], " ") != expected {
+		t.Errorf("expected %q, got %q", expected, lines[1])
+	}
+	if expected := "└── " + color.New(color.FgBlue).Sprint("child2"); strings.TrimRight(lines[2], " ") != expected {
+		t.Errorf("expected own colors to win, got %q", lines[2])
+	}
+}
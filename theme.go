@@ -0,0 +1,77 @@
+package gree
+
+// Theme holds the glyphs used to draw the connectors of a
+// tree. Each glyph takes up a single column. Fields left as
+// zero use the glyph of DefaultTheme. Inverted drawings only
+// flip the default LastBranch, Stack and BranchMark glyphs
+// and borders keep their box drawing corners.
+type Theme struct {
+	Vertical   rune // continues a branch down to the next sibling, '│'
+	Horizontal rune // leads from a branch to a label, '─'
+	Branch     rune // connects a node with siblings after it, '├'
+	LastBranch rune // connects the last child of a node, '└'
+	Stack      rune // turns down into a label when Stacked, '┐'
+	BranchMark rune // where children branch off with ParentBranchMark, '┬'
+	Stub       rune // marks rows continued below, '┆'
+	Arrow      rune // ends the connector when ArrowStyle is set, '▶'
+}
+
+// DefaultTheme holds the glyphs used when no other theme is
+// set with SetDefaultTheme
+var DefaultTheme = Theme{
+	Vertical:   '│',
+	Horizontal: '─',
+	Branch:     '├',
+	LastBranch: '└',
+	Stack:      '┐',
+	BranchMark: '┬',
+	Stub:       '┆',
+	Arrow:      '▶',
+}
+
+// these are process wide, see SetDefaultTheme and SetDefaultPadding
+var (
+	theme          = DefaultTheme
+	defaultPadding = "   "
+)
+
+// SetDefaultTheme sets the glyphs used to draw every tree.
+// Passing nil restores DefaultTheme. This is process wide and
+// not safe to call while other goroutines draw, set it once
+// at program start.
+func SetDefaultTheme(t *Theme) {
+	theme = DefaultTheme
+	if t == nil {
+		return
+	}
+	for _, glyph := range []struct {
+		set rune
+		to  *rune
+	}{
+		{t.Vertical, &theme.Vertical},
+		{t.Horizontal, &theme.Horizontal},
+		{t.Branch, &theme.Branch},
+		{t.LastBranch, &theme.LastBranch},
+		{t.Stack, &theme.Stack},
+		{t.BranchMark, &theme.BranchMark},
+		{t.Stub, &theme.Stub},
+		{t.Arrow, &theme.Arrow},
+	} {
+		if glyph.set != 0 {
+			*glyph.to = glyph.set
+		}
+	}
+}
+
+// SetDefaultPadding sets the padding NewNode gives new nodes,
+// "   " (3 spaces) by default. Existing nodes keep theirs. It
+// fails like SetPadding. This is process wide and not safe to
+// call while other goroutines create nodes, set it once at
+// program start.
+func SetDefaultPadding(padding string) error {
+	if err := checkPadding(padding); err != nil {
+		return err
+	}
+	defaultPadding = padding
+	return nil
+}
@@ -0,0 +1,56 @@
+package gree
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetDefaultTheme(t *testing.T) {
+	defer SetDefaultTheme(nil)
+	SetDefaultTheme(&Theme{Vertical: '|', Horizontal: '-', Branch: '+', LastBranch: '`'})
+	a := NewNode("root")
+	a.NewChild("child1").NewChild("grandchild1")
+	a.NewChild("child2")
+	expected := []string{
+		"root",
+		"+-- child1",
+		"|   `-- grandchild1",
+		"`-- child2",
+	}
+	got := a.DrawLines(&DrawInput{})
+	for i, e := range expected {
+		if strings.TrimRight(got[i], " ") != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+		}
+	}
+	if got := a.DrawLines(&DrawInput{Stacked: true}); strings.TrimRight(got[1], " ") != "+---┐" {
+		t.Errorf("expected unset glyphs to use the default theme, got '%s'", got[1])
+	}
+	SetDefaultTheme(nil)
+	if got := a.DrawLines(&DrawInput{}); strings.TrimRight(got[1], " ") != "├── child1" {
+		t.Errorf("expected default theme to be restored, got '%s'", got[1])
+	}
+}
+
+func TestSetDefaultPadding(t *testing.T) {
+	defer SetDefaultPadding("   ")
+	if err := SetDefaultPadding("\t"); err == nil {
+		t.Errorf("expected error setting tab padding")
+	}
+	if err := SetDefaultPadding("     "); err != nil {
+		t.Fatal(err)
+	}
+	a := NewNode("root")
+	a.NewChild("child1").NewChild("grandchild1")
+	expected := []string{
+		"root",
+		"└──── child1",
+		"      └──── grandchild1",
+	}
+	got := a.DrawLines(&DrawInput{})
+	for i, e := range expected {
+		if strings.TrimRight(got[i], " ") != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package gree
+
+import "iter"
+
+// Walk calls fn for this node and each of its descendents,
+// depth first with each node before its children and the
+// children in the order of GetChild. That is the top to
+// bottom order they are drawn in unless SetDrawOrder is
+// used, which Walk ignores. The walk stops as soon as fn returns false. The tree should not be
+// modified from within fn, use WalkSafe for that.
+func (n *Node) Walk(fn func(node *Node) bool) {
+	if n == nil {
+		return
+	}
+	n.walk(fn)
+}
+
+func (n *Node) walk(fn func(node *Node) bool) bool {
+	if !fn(n) {
+		return false
+	}
+	for _, child := range n.children {
+		if !child.walk(fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// WalkWithPath is like Walk but also passes fn the ancestors
+// of each node, starting with this node and ending with the
+// node's parent (so this node gets an empty path). Each call
+// gets its own copy of the path which is safe to keep.
+func (n *Node) WalkWithPath(fn func(node *Node, path []*Node) bool) {
+	if n == nil {
+		return
+	}
+	n.walkWithPath(fn, nil)
+}
+
+func (n *Node) walkWithPath(fn func(node *Node, path []*Node) bool, path []*Node) bool {
+	if !fn(n, append([]*Node(nil), path...)) {
+		return false
+	}
+	path = append(path, n)
+	for _, child := range n.children {
+		if !child.walkWithPath(fn, path) {
+			return false
+		}
+	}
+	return true
+}
+
+// WalkLeaves is like WalkWithPath but only calls fn for the
+// nodes without children, passing the path from this node
+// down to and including the leaf. The walk stops as soon as
+// fn returns false.
+func (n *Node) WalkLeaves(fn func(leaf *Node, path []*Node) bool) {
+	n.WalkWithPath(func(node *Node, path []*Node) bool {
+		if len(node.children) > 0 {
+			return true
+		}
+		return fn(node, append(path, node))
+	})
+}
+
+// WalkAction tells WalkSafe how to proceed after visiting a node
+type WalkAction int
+
+const (
+	// Continue visits the node's children and carries on
+	Continue WalkAction = iota
+	// SkipChildren carries on without visiting the node's children
+	SkipChildren
+	// RemoveNode removes the node and its descendents from the
+	// tree once the walk is finished. Its children are not visited.
+	RemoveNode
+	// Stop ends the walk. Removals requested so far are still applied.
+	Stop
+)
+
+// WalkSafe calls fn for this node and each of its descendents
+// in the same order as Walk, but lets fn change the tree
+// while walking. Each node's children are captured right after
+// the node is visited, so children fn adds to the node it was
+// passed are visited while changes elsewhere only take effect
+// for nodes not yet reached. Removals are deferred until the
+// walk is finished so they never disturb the traversal.
+func (n *Node) WalkSafe(fn func(node *Node) (action WalkAction)) {
+	if n == nil {
+		return
+	}
+	var removals []*Node
+	n.walkSafe(fn, &removals)
+	for _, node := range removals {
+		if node.parent != nil {
+			node.parent.RemoveChild(node.parent.childIndex(node))
+		}
+	}
+}
+
+func (n *Node) walkSafe(fn func(node *Node) WalkAction, removals *[]*Node) bool {
+	switch fn(n) {
+	case Stop:
+		return false
+	case SkipChildren:
+		return true
+	case RemoveNode:
+		*removals = append(*removals, n)
+		return true
+	}
+	children := make([]*Node, len(n.children))
+	copy(children, n.children)
+	for _, child := range children {
+		if !child.walkSafe(fn, removals) {
+			return false
+		}
+	}
+	return true
+}
+
+// Left returns the first child of this node, treating it
+// as a binary tree node. It returns nil if there is none.
+func (n *Node) Left() *Node {
+	return n.GetChild(0)
+}
+
+// Right returns the second child of this node, treating it
+// as a binary tree node. It returns nil if there is none.
+func (n *Node) Right() *Node {
+	return n.GetChild(1)
+}
+
+// WalkInOrder calls fn for every node of a binary tree in
+// order, visiting the Left subtree, then the node, then the
+// Right subtree. It is meant for nodes with at most two
+// children; any children past the second are walked in order
+// after the Right subtree as if they were further right
+// children.
+func (n *Node) WalkInOrder(fn func(node *Node)) {
+	if n == nil {
+		return
+	}
+	if len(n.children) == 0 {
+		fn(n)
+		return
+	}
+	n.children[0].WalkInOrder(fn)
+	fn(n)
+	for _, child := range n.children[1:] {
+		child.WalkInOrder(fn)
+	}
+}
+
+// Generations yields each depth (relative to this node, which
+// is depth 0) with the nodes at that depth in the order of
+// GetChild, down to MaxDepth. The tree is traversed once, each level is
+// built from the one before it.
+func (n *Node) Generations() iter.Seq2[int, []*Node] {
+	return func(yield func(int, []*Node) bool) {
+		if n == nil {
+			return
+		}
+		gen := []*Node{n}
+		for depth := 0; len(gen) > 0; depth++ {
+			if !yield(depth, gen) {
+				return
+			}
+			var next []*Node
+			for _, node := range gen {
+				next = append(next, node.children...)
+			}
+			gen = next
+		}
+	}
+}
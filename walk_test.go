@@ -0,0 +1,167 @@
+package gree
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1").NewChild("grandchild1")
+	a.NewChild("child2")
+	a.NewChild("child3")
+	var got []string
+	a.Walk(func(n *Node) bool {
+		got = append(got, n.String())
+		return n.String() != "child2"
+	})
+	expected := "root,child1,grandchild1,child2"
+	if strings.Join(got, ",") != expected {
+		t.Errorf("expected '%s', got '%s'", expected, strings.Join(got, ","))
+	}
+}
+
+func TestWalkSafe(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1").NewChild("prune-me").NewChild("never-visited")
+	a.NewChild("prune-me")
+	a.NewChild("child3").NewChild("skipped")
+	var visited []string
+	a.WalkSafe(func(n *Node) WalkAction {
+		visited = append(visited, n.String())
+		switch {
+		case n.String() == "prune-me":
+			return RemoveNode
+		case n.String() == "child3":
+			n.NewChild("added")
+			return SkipChildren
+		}
+		return Continue
+	})
+	expected := "root,child1,prune-me,prune-me,child3"
+	if strings.Join(visited, ",") != expected {
+		t.Errorf("expected visits '%s', got '%s'", expected, strings.Join(visited, ","))
+	}
+	if a.NumChildren() != 2 || a.GetChild(0).NumChildren() != 0 {
+		t.Errorf("expected both prune-me nodes to be removed")
+	}
+	if a.GetChild(1).NumChildren() != 2 {
+		t.Errorf("expected node added during walk to be kept")
+	}
+}
+
+func TestWalkInOrder(t *testing.T) {
+	a := NewNode("4")
+	b := a.NewChild("2")
+	b.NewChild("1")
+	b.NewChild("3")
+	a.NewChild("6").NewChild("5")
+	var got []string
+	a.WalkInOrder(func(n *Node) {
+		got = append(got, n.String())
+	})
+	if strings.Join(got, ",") != "1,2,3,4,5,6" {
+		t.Errorf("expected in order traversal '1,2,3,4,5,6', got '%s'", strings.Join(got, ","))
+	}
+	if a.Left() != b || a.Right().Left().String() != "5" || b.Left().Right() != nil {
+		t.Errorf("expected Left and Right to return the first and second children")
+	}
+}
+
+func TestWalkWithPath(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1").NewChild("grandchild1")
+	a.NewChild("child2").NewChild("grandchild2")
+	got := make(map[string]string)
+	var kept [][]*Node
+	a.WalkWithPath(func(n *Node, path []*Node) bool {
+		var names []string
+		for _, p := range path {
+			names = append(names, p.String())
+		}
+		got[n.String()] = strings.Join(append(names, n.String()), "/")
+		kept = append(kept, path)
+		return n.String() != "grandchild2"
+	})
+	expected := map[string]string{
+		"root":        "root",
+		"child1":      "root/child1",
+		"grandchild1": "root/child1/grandchild1",
+		"child2":      "root/child2",
+		"grandchild2": "root/child2/grandchild2",
+	}
+	for k, v := range expected {
+		if got[k] != v {
+			t.Errorf("expected '%s', got '%s'", v, got[k])
+		}
+	}
+	// paths kept from earlier calls must not be overwritten
+	if len(kept[2]) != 2 || kept[2][1].String() != "child1" {
+		t.Errorf("expected retained path to be unchanged")
+	}
+}
+
+func TestGenerations(t *testing.T) {
+	a := NewNode("root")
+	b := a.NewChild("child1")
+	b.NewChild("grandchild1")
+	a.NewChild("child2").NewChild("grandchild2").NewChild("greatgrandchild1")
+	expected := []string{"root", "child1,child2", "grandchild1,grandchild2", "greatgrandchild1"}
+	var got []string
+	for depth, nodes := range a.Generations() {
+		if depth != len(got) {
+			t.Errorf("expected depth %d, got %d", len(got), depth)
+		}
+		var names []string
+		for _, node := range nodes {
+			names = append(names, node.String())
+		}
+		got = append(got, strings.Join(names, ","))
+	}
+	if strings.Join(got, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected '%s', got '%s'", strings.Join(expected, "|"), strings.Join(got, "|"))
+	}
+	for depth, nodes := range a.Generations() {
+		if depth == 1 && nodes[0] != b {
+			t.Errorf("expected the real node pointers")
+		}
+		if depth == 1 {
+			break
+		}
+	}
+	var nilNode *Node
+	for range nilNode.Generations() {
+		t.Errorf("expected no generations for a nil node")
+	}
+}
+
+func TestWalkLeaves(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("child1").NewChild("grandchild1")
+	a.NewChild("child2")
+	a.NewChild("child3").NewChild("grandchild2")
+	var got []string
+	a.WalkLeaves(func(leaf *Node, path []*Node) bool {
+		var names []string
+		for _, p := range path {
+			names = append(names, p.String())
+		}
+		got = append(got, strings.Join(names, "/"))
+		return leaf.String() != "child2"
+	})
+	expected := "root/child1/grandchild1,root/child2"
+	if strings.Join(got, ",") != expected {
+		t.Errorf("expected '%s', got '%s'", expected, strings.Join(got, ","))
+	}
+	var leaves int
+	NewNode("alone").WalkLeaves(func(leaf *Node, path []*Node) bool {
+		leaves++
+		if len(path) != 1 || path[0] != leaf {
+			t.Errorf("expected a lone node to be its own path")
+		}
+		return true
+	})
+	if leaves != 1 {
+		t.Errorf("expected 1 leaf, got %d", leaves)
+	}
+}
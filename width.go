@@ -0,0 +1,96 @@
+package gree
+
+import (
+	"regexp"
+)
+
+// ansiEscape matches the SGR escape sequences used for colors
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// wideTail fills the column taken by the second half of a
+// wide rune in a rendered row
+const wideTail = -1
+
+// VisibleWidth returns the number of columns s takes up when
+// displayed, ignoring ANSI color sequences. East Asian wide
+// runes and most emoji count as two columns. This is the
+// width gree uses to lay out labels.
+func VisibleWidth(s string) (width int) {
+	for _, r := range ansiEscape.ReplaceAllString(s, "") {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// wideRanges are the code points displayed two columns wide
+var wideRanges = []struct{ lo, hi rune }{
+	{0x1100, 0x115F}, // Hangul Jamo
+	// emoji of the 0x2300 and 0x2600 blocks (⌛, ✅), the rest
+	// of them, such as ✓ and ✗, take up a single column
+	{0x231A, 0x231B},
+	{0x23E9, 0x23EC},
+	{0x23F0, 0x23F0},
+	{0x23F3, 0x23F3},
+	{0x25FD, 0x25FE},
+	{0x2614, 0x2615},
+	{0x2648, 0x2653},
+	{0x267F, 0x267F},
+	{0x2693, 0x2693},
+	{0x26A1, 0x26A1},
+	{0x26AA, 0x26AB},
+	{0x26BD, 0x26BE},
+	{0x26C4, 0x26C5},
+	{0x26CE, 0x26CE},
+	{0x26D4, 0x26D4},
+	{0x26EA, 0x26EA},
+	{0x26F2, 0x26F3},
+	{0x26F5, 0x26F5},
+	{0x26FA, 0x26FA},
+	{0x26FD, 0x26FD},
+	{0x2705, 0x2705},
+	{0x270A, 0x270B},
+	{0x2728, 0x2728},
+	{0x274C, 0x274C},
+	{0x274E, 0x274E},
+	{0x2753, 0x2755},
+	{0x2757, 0x2757},
+	{0x2795, 0x2797},
+	{0x27B0, 0x27B0},
+	{0x27BF, 0x27BF},
+	{0x2E80, 0x303E},   // CJK radicals, punctuation
+	{0x3041, 0x33FF},   // Hiragana, Katakana, CJK compatibility
+	{0x3400, 0x4DBF},   // CJK extension A
+	{0x4E00, 0x9FFF},   // CJK unified ideographs
+	{0xA000, 0xA4CF},   // Yi
+	{0xAC00, 0xD7A3},   // Hangul syllables
+	{0xF900, 0xFAFF},   // CJK compatibility ideographs
+	{0xFE30, 0xFE4F},   // CJK compatibility forms
+	{0xFF00, 0xFF60},   // fullwidth forms
+	{0xFFE0, 0xFFE6},   // fullwidth signs
+	{0x1F300, 0x1F64F}, // pictographs, emoticons
+	{0x1F900, 0x1F9FF}, // supplemental pictographs
+	{0x20000, 0x3FFFD}, // CJK extensions B and up
+}
+
+// runeWidth returns the number of columns r is displayed in
+func runeWidth(r rune) int {
+	for _, wide := range wideRanges {
+		if r >= wide.lo && r <= wide.hi {
+			return 2
+		}
+	}
+	return 1
+}
+
+// truncateWidth returns the longest prefix of s that fits in
+// width columns
+func truncateWidth(s string, width int) string {
+	used := 0
+	for i, r := range s {
+		if used+runeWidth(r) > width {
+			return s[:i]
+		}
+		used += runeWidth(r)
+	}
+	return s
+}
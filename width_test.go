@@ -0,0 +1,60 @@
+package gree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestVisibleWidth(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+	tests := map[string]int{
+		"":                                  0,
+		"child1":                            6,
+		color.New(color.FgRed).Sprint("ab"): 2,
+		"日本語":                               6,
+		"a日b":                               4,
+		"🌲 tree":                            7,
+		"⌛✅❌":                               6,
+	}
+	for s, expected := range tests {
+		if got := VisibleWidth(s); got != expected {
+			t.Errorf("expected width of %q to be %d, got %d", s, expected, got)
+		}
+	}
+}
+
+func TestDrawWideRunes(t *testing.T) {
+	a := NewNode("root")
+	a.NewChild("日本語").NewChild("abc")
+	a.NewChild("child2")
+	got := strings.Split(strings.TrimSuffix(a.DrawOptions(&DrawInput{Border: true}), "\n"), "\n")
+	expected := []string{
+		"┌────────────┐",
+		"│ root       │",
+		"│ ├── 日本語 │",
+		"│ │   └── abc│",
+		"│ └── child2 │",
+		"└────────────┘",
+	}
+	for i, e := range expected {
+		if got[i] != e {
+			t.Errorf("line %d, expected '%s', got '%s'", i, e, got[i])
+		}
+		if VisibleWidth(got[i]) != VisibleWidth(got[0]) {
+			t.Errorf("line %d has width %d, expected %d", i, VisibleWidth(got[i]), VisibleWidth(got[0]))
+		}
+	}
+}
+
+func TestStatusGlyphWidths(t *testing.T) {
+	expected := map[Status]int{Pending: 2, Running: 1, Success: 1, Failure: 1, Skipped: 1}
+	for status, style := range DefaultStatusTheme {
+		if got := VisibleWidth(style.Glyph); got != expected[status] {
+			t.Errorf("expected %s glyph %q to be %d columns, got %d", status, style.Glyph, expected[status], got)
+		}
+	}
+}